@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/justinas/alice"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+/*
+adminRoutes is the handler for the admin listener. Everything on it is for operators rather than
+users, so it lives on a separate plain HTTP address (localhost by default) instead of the public
+router:
+
+	/metrics        Prometheus metrics
+	/healthz        liveness check
+	/readyz         readiness check, with why each failing check fails
+	/debug/pprof/   the runtime profiler
+*/
+func (app *application) adminRoutes() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.Handler())
+	mux.HandleFunc("GET /healthz", app.healthz)
+	mux.HandleFunc("GET /readyz", app.adminReadyz)
+
+	// Registered one by one rather than by importing net/http/pprof for its
+	// side effects, which only adds them to http.DefaultServeMux.
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return alice.New(app.requestID, app.logRequest, app.recoverPanic, app.requireAdminAuth).Then(mux)
+}
+
+/*
+requireAdminAuth checks the credentials configured in the admin section. With a username and
+password it accepts HTTP basic auth, with a token it accepts "Authorization: Bearer <token>", and
+with both it accepts either. With neither the listener is open to anyone who can reach it, which
+is only sensible on a loopback address.
+*/
+func (app *application) requireAdminAuth(next http.Handler) http.Handler {
+	cfg := app.config.Admin
+	if cfg.Username == "" && cfg.Token == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := bearerToken(r); ok && cfg.Token != "" && secureCompare(token, cfg.Token) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if username, password, ok := r.BasicAuth(); ok && cfg.Username != "" &&
+			secureCompare(username, cfg.Username) && secureCompare(password, cfg.Password) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if cfg.Username != "" {
+			w.Header().Add("WWW-Authenticate", `Basic realm="admin", charset="UTF-8"`)
+		}
+		if cfg.Token != "" {
+			w.Header().Add("WWW-Authenticate", "Bearer")
+		}
+		app.clientError(w, http.StatusUnauthorized)
+	})
+}
+
+// secureCompare reports whether a and b are equal in constant time. Hashing
+// first hides the length of the expected value as well.
+func secureCompare(a, b string) bool {
+	ha := sha256.Sum256([]byte(a))
+	hb := sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
+
+// isLoopback reports whether addr only listens on a loopback interface.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
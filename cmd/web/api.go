@@ -0,0 +1,340 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+	"github.com/Vanshikav123/ByteFlow.git/internal/validator"
+)
+
+/*
+The JSON API mirrors the HTML handlers but every response, including errors, is a JSON
+object. Errors always look like:
+
+	{"error": {"status": 422, "message": "...", "fields": {"title": "..."}}}
+
+where "fields" and "errors" carry the validator.Validator field and non-field errors.
+*/
+
+func (app *application) apiErrorResponse(w http.ResponseWriter, status int, message string, v *validator.Validator) {
+	body := envelope{
+		"status":  status,
+		"message": message,
+	}
+	if v != nil {
+		if len(v.FieldErrors) > 0 {
+			body["fields"] = v.FieldErrors
+		}
+		if len(v.NonFieldErrors) > 0 {
+			body["errors"] = v.NonFieldErrors
+		}
+	}
+	err := app.writeJSON(w, status, envelope{"error": body}, nil)
+	if err != nil {
+		app.logger.Error("writing error response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// apiServerError is the JSON counterpart of serverError.
+func (app *application) apiServerError(w http.ResponseWriter, r *http.Request, err error) {
+	app.logServerError(r, err)
+	app.apiErrorResponse(w, http.StatusInternalServerError, "the server encountered a problem and could not process your request", nil)
+}
+
+func (app *application) apiClientError(w http.ResponseWriter, status int, message string) {
+	app.apiErrorResponse(w, status, message, nil)
+}
+
+func (app *application) apiNotFound(w http.ResponseWriter) {
+	app.apiClientError(w, http.StatusNotFound, "the requested resource could not be found")
+}
+
+func (app *application) apiFailedValidation(w http.ResponseWriter, v *validator.Validator) {
+	app.apiErrorResponse(w, http.StatusUnprocessableEntity, "the request contains invalid fields", v)
+}
+
+// apiModelError maps the models sentinel errors onto API responses.
+func (app *application) apiModelError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, models.ErrNoRecord):
+		app.apiNotFound(w)
+	case errors.Is(err, models.ErrInvalidCredentials):
+		app.apiClientError(w, http.StatusUnauthorized, "invalid authentication credentials")
+	case errors.Is(err, models.ErrDuplicateEmail):
+		v := &validator.Validator{}
+		v.AddFieldError("email", "Email address is already in use")
+		app.apiFailedValidation(w, v)
+	default:
+		app.apiServerError(w, r, err)
+	}
+}
+
+// requireAPIAuthentication is requireAuthentication for API routes: instead of
+// redirecting to the login page it answers 401.
+func (app *application) requireAPIAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.isAuthenticated(r) {
+			app.apiClientError(w, http.StatusUnauthorized, "you must be authenticated to access this resource")
+			return
+		}
+		w.Header().Add("Cache-Control", "no-store")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// apiLoadSnippet and apiOwnedSnippet are the JSON counterparts of loadSnippet
+// and ownedSnippet.
+func (app *application) apiLoadSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	// Unlike the pages, the API never took the old numeric ids: clients
+	// have always been given the id field of the snippet, now its slug.
+	snippet, err := app.snippets.GetBySlug(r.Context(), snippetSlugParam(r))
+	if err != nil {
+		app.apiModelError(w, r, err)
+		return nil, false
+	}
+	if !snippet.VisibleTo(app.authenticatedUserID(r)) {
+		app.apiNotFound(w)
+		return nil, false
+	}
+	return snippet, true
+}
+
+func (app *application) apiOwnedSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	snippet, ok := app.apiLoadSnippet(w, r)
+	if !ok {
+		return nil, false
+	}
+	if snippet.UserID != app.authenticatedUserID(r) {
+		app.apiClientError(w, http.StatusForbidden, "you do not have permission to modify this snippet")
+		return nil, false
+	}
+	return snippet, true
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// GET /api/v1/snippets?tag=go&sort=newest&page_size=20&cursor=...
+//
+// Takes the filters of parseListing. Pages are reached through
+// metadata.next_cursor rather than page numbers, which only the first page
+// still accepts.
+func (app *application) apiSnippetList(w http.ResponseWriter, r *http.Request) {
+	form := parseListing(r)
+	page, err := queryInt(r, "page", 1)
+	form.CheckField(err == nil && page == 1, "page", "This field is no longer supported, use cursor with metadata.next_cursor")
+	pageSize, err := queryInt(r, "page_size", defaultPageSize)
+	form.CheckField(err == nil && pageSize >= 1 && pageSize <= maxPageSize, "page_size", fmt.Sprintf("This field must be between 1 and %d", maxPageSize))
+	if !form.Valid() {
+		app.apiFailedValidation(w, &form.Validator)
+		return
+	}
+
+	result, err := app.snippets.Find(r.Context(), form.query(app.authenticatedUserID(r), pageSize))
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			form.AddFieldError("cursor", "This field must be a next_cursor returned with the same sort order")
+			app.apiFailedValidation(w, &form.Validator)
+			return
+		}
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"snippets": result.Snippets,
+		"metadata": envelope{
+			"page_size":   pageSize,
+			"has_more":    result.Next != "",
+			"next_cursor": result.Next,
+		},
+	}, nil)
+	if err != nil {
+		app.apiServerError(w, r, err)
+	}
+}
+
+// GET /api/v1/search?q=words&page=1&page_size=20
+func (app *application) apiSearch(w http.ResponseWriter, r *http.Request) {
+	var v validator.Validator
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	v.CheckField(validator.NotBlank(query), "q", "This field cannot be blank")
+	v.CheckField(validator.MaxChars(query, maxQueryLength), "q", fmt.Sprintf("This field cannot be more than %d characters long", maxQueryLength))
+	page, err := queryInt(r, "page", 1)
+	v.CheckField(err == nil && page >= 1, "page", "This field must be a positive integer")
+	pageSize, err := queryInt(r, "page_size", defaultPageSize)
+	v.CheckField(err == nil && pageSize >= 1 && pageSize <= maxPageSize, "page_size", fmt.Sprintf("This field must be between 1 and %d", maxPageSize))
+	if !v.Valid() {
+		app.apiFailedValidation(w, &v)
+		return
+	}
+
+	results, err := app.search.Search(r.Context(), query, app.authenticatedUserID(r), pageSize+1, (page-1)*pageSize)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	hasMore := len(results) > pageSize
+	if hasMore {
+		results = results[:pageSize]
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"results": results,
+		"metadata": envelope{
+			"query":     query,
+			"page":      page,
+			"page_size": pageSize,
+			"has_more":  hasMore,
+		},
+	}, nil)
+	if err != nil {
+		app.apiServerError(w, r, err)
+	}
+}
+
+// GET /api/v1/snippets/:slug
+//
+// Fetching a snippet with a view limit that isn't one's own uses up a view,
+// with no warning first: API clients know what they're asking for. The
+// password of a snippet that has one is sent in the X-Snippet-Password header,
+// unless it was given on the snippet's page in the same session, and wrong ones
+// are limited the same way.
+func (app *application) apiSnippetGet(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiLoadSnippet(w, r)
+	if !ok {
+		return
+	}
+	if app.locked(r, snippet) {
+		password := r.Header.Get("X-Snippet-Password")
+		if password == "" {
+			app.apiClientError(w, http.StatusUnauthorized, "this snippet is password protected: send its password in the X-Snippet-Password header")
+			return
+		}
+		if ok, wait := app.unlockLimiter.Reserve(snippet.ID); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(wait/time.Second)+1))
+			app.apiClientError(w, http.StatusTooManyRequests, "too many wrong passwords have been tried for this snippet")
+			return
+		}
+		match, err := snippet.PasswordMatches(password)
+		if err != nil {
+			app.unlockLimiter.Refund(snippet.ID)
+			app.apiServerError(w, r, err)
+			return
+		}
+		if !match {
+			app.apiClientError(w, http.StatusUnauthorized, "the snippet password is incorrect")
+			return
+		}
+		app.unlockLimiter.Refund(snippet.ID)
+	}
+	headers := make(http.Header)
+	if app.consumable(r, snippet) {
+		var err error
+		snippet, err = app.snippets.Consume(r.Context(), snippet.ID)
+		if err != nil {
+			app.apiModelError(w, r, err)
+			return
+		}
+		headers.Set("Cache-Control", "no-store")
+	}
+	err := app.writeJSON(w, http.StatusOK, envelope{"snippet": snippet}, headers)
+	if err != nil {
+		app.apiServerError(w, r, err)
+	}
+}
+
+// POST /api/v1/snippets
+func (app *application) apiSnippetCreate(w http.ResponseWriter, r *http.Request) {
+	var input snippetCreateForm
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.apiClientError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	input.validate()
+	if !input.Valid() {
+		app.apiFailedValidation(w, &input.Validator)
+		return
+	}
+
+	id, slug, err := app.snippets.Insert(r.Context(), app.authenticatedUserID(r), input.fields())
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	app.metrics.snippetsCreated.WithLabelValues("api").Inc()
+	snippet, err := app.snippets.Get(r.Context(), id)
+	if err != nil {
+		app.apiModelError(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api/v1/snippets/%s", slug))
+	err = app.writeJSON(w, http.StatusCreated, envelope{"snippet": snippet}, headers)
+	if err != nil {
+		app.apiServerError(w, r, err)
+	}
+}
+
+// PUT /api/v1/snippets/:slug
+func (app *application) apiSnippetUpdate(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiOwnedSnippet(w, r)
+	if !ok {
+		return
+	}
+	var input snippetCreateForm
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.apiClientError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// As with the edit form, the view limit and password are the ones set on
+	// creation.
+	input.MaxViews = snippet.MaxViews
+	input.Password = ""
+	input.protected = snippet.Protected()
+	input.validate()
+	if !input.Valid() {
+		app.apiFailedValidation(w, &input.Validator)
+		return
+	}
+
+	err = app.snippets.Update(r.Context(), snippet.ID, app.authenticatedUserID(r), input.fields())
+	if err != nil {
+		app.apiModelError(w, r, err)
+		return
+	}
+	snippet, err = app.snippets.Get(r.Context(), snippet.ID)
+	if err != nil {
+		app.apiModelError(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"snippet": snippet}, nil)
+	if err != nil {
+		app.apiServerError(w, r, err)
+	}
+}
+
+// DELETE /api/v1/snippets/:slug
+func (app *application) apiSnippetDelete(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiOwnedSnippet(w, r)
+	if !ok {
+		return
+	}
+	err := app.snippets.Delete(r.Context(), snippet.ID)
+	if err != nil {
+		app.apiModelError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
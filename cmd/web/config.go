@@ -0,0 +1,374 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+/*
+config holds every setting the server reads at startup. Values are layered, each one overriding
+the last:
+
+ 1. the defaults from defaultConfig
+ 2. a TOML, YAML or JSON file named by -config (or BYTEFLOW_CONFIG), picked by its extension
+ 3. environment variables, one per flag: -db-driver becomes BYTEFLOW_DB_DRIVER
+ 4. flags given on the command line
+
+so the same binary can run in every environment with only the file or the environment changed.
+See config.example.toml for the file layout.
+*/
+type config struct {
+	Addr            string   `json:"addr" toml:"addr" yaml:"addr"`
+	ShutdownTimeout duration `json:"shutdown_timeout" toml:"shutdown_timeout" yaml:"shutdown_timeout"`
+	ShutdownDelay   duration `json:"shutdown_delay" toml:"shutdown_delay" yaml:"shutdown_delay"`
+	LatestLimit     int      `json:"latest_limit" toml:"latest_limit" yaml:"latest_limit"`
+	CSP             string   `json:"csp" toml:"csp" yaml:"csp"`
+	BcryptCost      int      `json:"bcrypt_cost" toml:"bcrypt_cost" yaml:"bcrypt_cost"`
+
+	DB struct {
+		Driver string `json:"driver" toml:"driver" yaml:"driver"`
+		DSN    string `json:"dsn" toml:"dsn" yaml:"dsn"`
+	} `json:"db" toml:"db" yaml:"db"`
+
+	TLS struct {
+		CertFile       string   `json:"cert_file" toml:"cert_file" yaml:"cert_file"`
+		KeyFile        string   `json:"key_file" toml:"key_file" yaml:"key_file"`
+		ReloadInterval duration `json:"reload_interval" toml:"reload_interval" yaml:"reload_interval"`
+		RedirectAddr   string   `json:"redirect_addr" toml:"redirect_addr" yaml:"redirect_addr"`
+		// RedirectHosts are the host names the redirect listener sends to
+		// HTTPS, besides tls.acme.domains.
+		RedirectHosts stringList `json:"redirect_hosts" toml:"redirect_hosts" yaml:"redirect_hosts"`
+
+		ACME struct {
+			Enabled      bool       `json:"enabled" toml:"enabled" yaml:"enabled"`
+			Domains      stringList `json:"domains" toml:"domains" yaml:"domains"`
+			Email        string     `json:"email" toml:"email" yaml:"email"`
+			DirectoryURL string     `json:"directory_url" toml:"directory_url" yaml:"directory_url"`
+			CacheDir     string     `json:"cache_dir" toml:"cache_dir" yaml:"cache_dir"`
+			CAFile       string     `json:"ca_file" toml:"ca_file" yaml:"ca_file"`
+		} `json:"acme" toml:"acme" yaml:"acme"`
+	} `json:"tls" toml:"tls" yaml:"tls"`
+
+	Timeouts struct {
+		Read  duration `json:"read" toml:"read" yaml:"read"`
+		Write duration `json:"write" toml:"write" yaml:"write"`
+		Idle  duration `json:"idle" toml:"idle" yaml:"idle"`
+	} `json:"timeouts" toml:"timeouts" yaml:"timeouts"`
+
+	Session struct {
+		Lifetime duration `json:"lifetime" toml:"lifetime" yaml:"lifetime"`
+	} `json:"session" toml:"session" yaml:"session"`
+
+	Admin struct {
+		Addr     string `json:"addr" toml:"addr" yaml:"addr"`
+		Username string `json:"username" toml:"username" yaml:"username"`
+		Password string `json:"password" toml:"password" yaml:"password"`
+		Token    string `json:"token" toml:"token" yaml:"token"`
+	} `json:"admin" toml:"admin" yaml:"admin"`
+
+	Tracing struct {
+		Exporter    string  `json:"exporter" toml:"exporter" yaml:"exporter"`
+		File        string  `json:"file" toml:"file" yaml:"file"`
+		Endpoint    string  `json:"endpoint" toml:"endpoint" yaml:"endpoint"`
+		Insecure    bool    `json:"insecure" toml:"insecure" yaml:"insecure"`
+		SampleRatio float64 `json:"sample_ratio" toml:"sample_ratio" yaml:"sample_ratio"`
+	} `json:"tracing" toml:"tracing" yaml:"tracing"`
+
+	Log struct {
+		Format string `json:"format" toml:"format" yaml:"format"`
+		Level  string `json:"level" toml:"level" yaml:"level"`
+	} `json:"log" toml:"log" yaml:"log"`
+
+	Reaper struct {
+		Interval duration `json:"interval" toml:"interval" yaml:"interval"`
+		Batch    int      `json:"batch" toml:"batch" yaml:"batch"`
+	} `json:"reaper" toml:"reaper" yaml:"reaper"`
+}
+
+// envPrefix is prepended to the upper-cased flag name to get its environment
+// variable.
+const envPrefix = "BYTEFLOW_"
+
+func defaultConfig() config {
+	var cfg config
+	cfg.Addr = ":4000"
+	cfg.ShutdownTimeout = duration(30 * time.Second)
+	cfg.LatestLimit = 10
+	cfg.CSP = "default-src 'self'; style-src 'self' fonts.googleapis.com; font-src fonts.gstatic.com"
+	cfg.BcryptCost = 12
+	cfg.DB.Driver = "mysql"
+	cfg.TLS.CertFile = "./tls/cert.pem"
+	cfg.TLS.KeyFile = "./tls/key.pem"
+	cfg.TLS.ReloadInterval = duration(30 * time.Second)
+	cfg.TLS.ACME.DirectoryURL = autocert.DefaultACMEDirectory
+	cfg.TLS.ACME.CacheDir = "./tls/acme"
+	cfg.Timeouts.Read = duration(5 * time.Second)
+	cfg.Timeouts.Write = duration(10 * time.Second)
+	cfg.Timeouts.Idle = duration(time.Minute)
+	cfg.Session.Lifetime = duration(12 * time.Hour)
+	cfg.Admin.Addr = "localhost:4001"
+	cfg.Tracing.Exporter = "none"
+	cfg.Tracing.File = "traces.jsonl"
+	cfg.Tracing.SampleRatio = 1
+	cfg.Log.Format = "text"
+	cfg.Log.Level = "info"
+	cfg.Reaper.Interval = duration(time.Minute)
+	cfg.Reaper.Batch = 500
+	return cfg
+}
+
+// bindFlags defines a flag on fs for every setting in cfg, using the current
+// values as defaults.
+func (cfg *config) bindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&cfg.Addr, "addr", cfg.Addr, "http network address")
+	fs.Var(&cfg.ShutdownTimeout, "shutdown-timeout", "how long to wait for in-flight requests when shutting down")
+	fs.Var(&cfg.ShutdownDelay, "shutdown-delay", "how long to keep accepting requests, with /readyz failing, before draining on shutdown")
+	fs.IntVar(&cfg.LatestLimit, "latest-limit", cfg.LatestLimit, "number of snippets on each page of the home page and your snippets")
+	fs.StringVar(&cfg.CSP, "csp", cfg.CSP, "Content-Security-Policy header sent with every response")
+	fs.IntVar(&cfg.BcryptCost, "bcrypt-cost", cfg.BcryptCost, "bcrypt cost for hashing new passwords")
+	fs.StringVar(&cfg.DB.Driver, "db-driver", cfg.DB.Driver, "storage backend (mysql, postgres, sqlite or memory)")
+	fs.StringVar(&cfg.DB.DSN, "dsn", cfg.DB.DSN, "data source name (defaults to a local snippetbox database for mysql and postgres, and file:byteflow.db for sqlite)")
+	fs.StringVar(&cfg.TLS.CertFile, "tls-cert", cfg.TLS.CertFile, "path to the TLS certificate")
+	fs.StringVar(&cfg.TLS.KeyFile, "tls-key", cfg.TLS.KeyFile, "path to the TLS private key")
+	fs.Var(&cfg.TLS.ReloadInterval, "tls-reload-interval", "how often to check the certificate files for changes (0 reloads on SIGHUP only)")
+	fs.StringVar(&cfg.TLS.RedirectAddr, "redirect-addr", cfg.TLS.RedirectAddr, "plain HTTP address that redirects to HTTPS and answers ACME challenges (empty disables it)")
+	fs.Var(&cfg.TLS.RedirectHosts, "redirect-hosts", "comma-separated host names the redirect listener redirects, besides the ACME domains")
+	fs.BoolVar(&cfg.TLS.ACME.Enabled, "acme", cfg.TLS.ACME.Enabled, "get certificates from an ACME server instead of tls-cert and tls-key")
+	fs.Var(&cfg.TLS.ACME.Domains, "acme-domains", "comma-separated domains to request certificates for")
+	fs.StringVar(&cfg.TLS.ACME.Email, "acme-email", cfg.TLS.ACME.Email, "contact email for the ACME account")
+	fs.StringVar(&cfg.TLS.ACME.DirectoryURL, "acme-directory", cfg.TLS.ACME.DirectoryURL, "directory URL of the ACME server")
+	fs.StringVar(&cfg.TLS.ACME.CacheDir, "acme-cache-dir", cfg.TLS.ACME.CacheDir, "directory to keep ACME certificates and the account key in")
+	fs.StringVar(&cfg.TLS.ACME.CAFile, "acme-ca-file", cfg.TLS.ACME.CAFile, "PEM roots to trust for the ACME server, for testing against a private one")
+	fs.Var(&cfg.Timeouts.Read, "read-timeout", "maximum duration for reading a request")
+	fs.Var(&cfg.Timeouts.Write, "write-timeout", "maximum duration for writing a response")
+	fs.Var(&cfg.Timeouts.Idle, "idle-timeout", "how long to keep idle keep-alive connections open")
+	fs.Var(&cfg.Session.Lifetime, "session-lifetime", "how long a login session lasts")
+	fs.StringVar(&cfg.Admin.Addr, "admin-addr", cfg.Admin.Addr, "plain HTTP address for metrics, health checks and pprof (empty disables it)")
+	fs.StringVar(&cfg.Admin.Username, "admin-user", cfg.Admin.Username, "basic auth username for the admin listener")
+	fs.StringVar(&cfg.Admin.Password, "admin-password", cfg.Admin.Password, "basic auth password for the admin listener")
+	fs.StringVar(&cfg.Admin.Token, "admin-token", cfg.Admin.Token, "bearer token accepted by the admin listener")
+	fs.StringVar(&cfg.Tracing.Exporter, "trace-exporter", cfg.Tracing.Exporter, "where to send traces (none, stdout, file or otlp)")
+	fs.StringVar(&cfg.Tracing.File, "trace-file", cfg.Tracing.File, "file the file trace exporter appends to")
+	fs.StringVar(&cfg.Tracing.Endpoint, "trace-endpoint", cfg.Tracing.Endpoint, "host:port of the OTLP/HTTP collector (defaults to OTEL_EXPORTER_OTLP_ENDPOINT)")
+	fs.BoolVar(&cfg.Tracing.Insecure, "trace-insecure", cfg.Tracing.Insecure, "send OTLP traces over plain HTTP")
+	fs.Float64Var(&cfg.Tracing.SampleRatio, "trace-sample-ratio", cfg.Tracing.SampleRatio, "fraction of new traces to sample, from 0 to 1")
+	fs.StringVar(&cfg.Log.Format, "log-format", cfg.Log.Format, "log output format (text or json)")
+	fs.StringVar(&cfg.Log.Level, "log-level", cfg.Log.Level, "minimum level to log (debug, info, warn or error)")
+	fs.Var(&cfg.Reaper.Interval, "reaper-interval", "how often to delete expired snippets")
+	fs.IntVar(&cfg.Reaper.Batch, "reaper-batch", cfg.Reaper.Batch, "maximum number of expired snippets to delete per statement")
+}
+
+/*
+loadConfig parses the command line flags and builds the config from them, the config file and the
+environment. The flags are parsed first, into a throwaway config, only to find the config file and
+to learn which flags were actually given; those are replayed last so they win over everything else.
+*/
+func loadConfig() (config, error) {
+	var configFile string
+	scratch := defaultConfig()
+	scratch.bindFlags(flag.CommandLine)
+	flag.StringVar(&configFile, "config", os.Getenv(envPrefix+"CONFIG"), "path to a .toml, .yaml or .json config file")
+	flag.Parse()
+
+	cfg := defaultConfig()
+	if configFile != "" {
+		if err := cfg.loadFile(configFile); err != nil {
+			return cfg, err
+		}
+	}
+
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	cfg.bindFlags(fs)
+
+	var errs []error
+	fs.VisitAll(func(f *flag.Flag) {
+		name := envPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		if value, ok := os.LookupEnv(name); ok {
+			if err := fs.Set(f.Name, value); err != nil {
+				errs = append(errs, fmt.Errorf("config: %s: %w", name, err))
+			}
+		}
+	})
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "config" {
+			return
+		}
+		// The value already parsed once, so it can't fail now.
+		fs.Set(f.Name, f.Value.String())
+	})
+	if len(errs) > 0 {
+		return cfg, errors.Join(errs...)
+	}
+
+	return cfg, cfg.validate()
+}
+
+// loadFile decodes the file at path over cfg. Keys the config doesn't know
+// about are an error, so a misspelt setting isn't silently ignored.
+func (cfg *config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".toml":
+		var md toml.MetaData
+		md, err = toml.Decode(string(data), cfg)
+		if err == nil {
+			if undecoded := md.Undecoded(); len(undecoded) > 0 {
+				err = fmt.Errorf("unknown key %q", undecoded[0].String())
+			}
+		}
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(cfg)
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(cfg)
+	default:
+		return fmt.Errorf("config: %s: unsupported file extension %q (want .toml, .yaml, .yml or .json)", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	return nil
+}
+
+// validate reports every invalid setting at once, naming each one the way
+// the config file does.
+func (cfg *config) validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("config: "+format, args...))
+		}
+	}
+
+	check(cfg.Addr != "", "addr must not be empty")
+	check(cfg.ShutdownTimeout > 0, "shutdown_timeout must be positive")
+	check(cfg.ShutdownDelay >= 0, "shutdown_delay must not be negative")
+	check(cfg.LatestLimit >= 1 && cfg.LatestLimit <= 100, "latest_limit must be between 1 and 100, got %d", cfg.LatestLimit)
+	check(cfg.BcryptCost >= bcrypt.MinCost && cfg.BcryptCost <= bcrypt.MaxCost,
+		"bcrypt_cost must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, cfg.BcryptCost)
+	check(slices.Contains([]string{"mysql", "postgres", "sqlite", "memory"}, cfg.DB.Driver), "db.driver must be one of mysql, postgres, sqlite or memory, got %q", cfg.DB.Driver)
+	if cfg.TLS.ACME.Enabled {
+		check(len(cfg.TLS.ACME.Domains) > 0, "tls.acme.domains must list at least one domain")
+		check(cfg.TLS.ACME.CacheDir != "", "tls.acme.cache_dir must not be empty")
+		u, err := url.Parse(cfg.TLS.ACME.DirectoryURL)
+		check(err == nil && u.Scheme == "https" && u.Host != "", "tls.acme.directory_url must be an https URL, got %q", cfg.TLS.ACME.DirectoryURL)
+	} else {
+		check(cfg.TLS.CertFile != "", "tls.cert_file must not be empty")
+		check(cfg.TLS.KeyFile != "", "tls.key_file must not be empty")
+	}
+	check(cfg.TLS.ReloadInterval >= 0, "tls.reload_interval must not be negative")
+	check(cfg.TLS.RedirectAddr == "" || (cfg.TLS.RedirectAddr != cfg.Addr && cfg.TLS.RedirectAddr != cfg.Admin.Addr),
+		"tls.redirect_addr must differ from addr and admin.addr")
+	check(cfg.TLS.RedirectAddr == "" || len(cfg.redirectHosts()) > 0,
+		"tls.redirect_addr needs tls.redirect_hosts or tls.acme.domains to know which hosts to redirect")
+	check(cfg.Timeouts.Read > 0, "timeouts.read must be positive")
+	check(cfg.Timeouts.Write > 0, "timeouts.write must be positive")
+	check(cfg.Timeouts.Idle > 0, "timeouts.idle must be positive")
+	check(cfg.Session.Lifetime > 0, "session.lifetime must be positive")
+	check((cfg.Admin.Username == "") == (cfg.Admin.Password == ""), "admin.username and admin.password must be set together")
+	check(cfg.Admin.Addr == "" || cfg.Admin.Addr != cfg.Addr, "admin.addr must differ from addr")
+	check(slices.Contains([]string{"none", "stdout", "file", "otlp"}, cfg.Tracing.Exporter),
+		"tracing.exporter must be one of none, stdout, file or otlp, got %q", cfg.Tracing.Exporter)
+	check(cfg.Tracing.Exporter != "file" || cfg.Tracing.File != "", "tracing.file must be set for the file exporter")
+	check(cfg.Tracing.SampleRatio >= 0 && cfg.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1, got %g", cfg.Tracing.SampleRatio)
+	check(cfg.Log.Format == "text" || cfg.Log.Format == "json", "log.format must be text or json, got %q", cfg.Log.Format)
+	var level slog.Level
+	check(level.UnmarshalText([]byte(cfg.Log.Level)) == nil, "log.level must be debug, info, warn or error, got %q", cfg.Log.Level)
+	check(cfg.Reaper.Interval > 0, "reaper.interval must be positive")
+	check(cfg.Reaper.Batch > 0, "reaper.batch must be positive")
+
+	return errors.Join(errs...)
+}
+
+// newLogger builds the application logger described by the log section. The
+// config must already have been validated.
+func (cfg *config) newLogger(w io.Writer) *slog.Logger {
+	var level slog.Level
+	level.UnmarshalText([]byte(cfg.Log.Level))
+	opts := &slog.HandlerOptions{Level: level}
+
+	if cfg.Log.Format == "json" {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// duration is a time.Duration written as a string like "30s" or "12h" in
+// config files, environment variables and flags alike.
+type duration time.Duration
+
+func (d duration) String() string {
+	return time.Duration(d).String()
+}
+
+// Set implements flag.Value.
+func (d *duration) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+// UnmarshalText is used by all three file decoders.
+func (d *duration) UnmarshalText(text []byte) error {
+	return d.Set(string(text))
+}
+
+func (d duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// redirectHosts are the host names the redirect listener redirects to HTTPS:
+// tls.redirect_hosts and, with ACME on, tls.acme.domains.
+func (cfg *config) redirectHosts() []string {
+	hosts := slices.Clone(cfg.TLS.RedirectHosts)
+	if cfg.TLS.ACME.Enabled {
+		hosts = append(hosts, cfg.TLS.ACME.Domains...)
+	}
+	return hosts
+}
+
+// stringList is a list of strings, written as an array in config files and
+// comma-separated in environment variables and flags.
+type stringList []string
+
+func (l stringList) String() string {
+	return strings.Join(l, ",")
+}
+
+// Set implements flag.Value. It replaces the list rather than appending to
+// it, so a flag overrides the file instead of adding to it.
+func (l *stringList) Set(s string) error {
+	*l = nil
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
@@ -7,3 +7,16 @@ Used to store/retrieve authentication status in Go’s context.Context.*/
 type contextKey string
 
 const isAuthenticatedContextKey = contextKey("isAuthenticated")
+
+// authenticatedUserIDContextKey holds the id of the user that the
+// authenticate middleware verified still exists.
+const authenticatedUserIDContextKey = contextKey("authenticatedUserID")
+
+// requestIDContextKey holds the ID the requestID middleware gave the request.
+const requestIDContextKey = contextKey("requestID")
+
+// serverSpanContextKey holds the span traceRequest started for the request.
+const serverSpanContextKey = contextKey("serverSpan")
+
+// routeContextKey holds the *requestRoute that instrument fills in.
+const routeContextKey = contextKey("route")
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+)
+
+/*
+The Atom feed (RFC 4287) of the snippets. It takes the same filters as the home page, so
+/feed.atom?tag=go follows the snippets tagged go, and lists the newest feedSize of them unless
+another sort order is asked for. Older entries are linked with rel="next", the paging of RFC 5005.
+
+Atom wants absolute URLs, and there's no configured public URL, so they're built from the Host
+header of the request.
+*/
+
+const feedSize = 20
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated time.Time   `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Published  time.Time      `xml:"published"`
+	Updated    time.Time      `xml:"updated"`
+	Author     atomPerson     `xml:"author"`
+	Links      []atomLink     `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// GET /feed.atom?tag=go
+func (app *application) feed(w http.ResponseWriter, r *http.Request) {
+	form := parseListing(r)
+	if !form.Valid() {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	// Feeds are fetched without a session and often shared, so they only
+	// ever list public snippets, whoever asks.
+	page, err := app.snippets.Find(r.Context(), form.query(0, feedSize))
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			app.clientError(w, http.StatusBadRequest)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	scheme := "https"
+	if r.TLS == nil {
+		scheme = "http"
+	}
+	base := scheme + "://" + r.Host
+	listing := &listingPage{Path: "/feed.atom", Form: form, Next: page.Next}
+
+	f := atomFeed{
+		ID:    base + listing.FeedURL(),
+		Title: "ByteFlow snippets",
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: base + r.URL.RequestURI()},
+			{Rel: "alternate", Type: "text/html", Href: base + form.url("/")},
+		},
+		Entries: []atomEntry{},
+	}
+	if form.Tag != "" {
+		f.Title = fmt.Sprintf("ByteFlow snippets tagged %s", form.Tag)
+	}
+	if page.Next != "" {
+		f.Links = append(f.Links, atomLink{Rel: "next", Type: "application/atom+xml", Href: base + listing.NextURL()})
+	}
+
+	for _, s := range page.Snippets {
+		url := fmt.Sprintf("%s/snippet/view/%s", base, s.Slug)
+		entry := atomEntry{
+			ID:        url,
+			Title:     s.Title,
+			Published: s.Created,
+			Updated:   s.Created,
+			Author:    atomPerson{Name: s.Author},
+			Links:     []atomLink{{Rel: "alternate", Type: "text/html", Href: url}},
+			Content:   atomContent{Type: "text", Text: s.Content},
+		}
+		for _, tag := range s.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		f.Entries = append(f.Entries, entry)
+		if s.Created.After(f.Updated) {
+			f.Updated = s.Created
+		}
+	}
+	if f.Updated.IsZero() {
+		f.Updated = time.Now().UTC().Truncate(time.Second)
+	}
+
+	body, err := xml.MarshalIndent(f, "", "  ")
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	w.Write(body)
+	w.Write([]byte("\n"))
+}
@@ -5,68 +5,361 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Vanshikav123/ByteFlow.git/internal/diff"
 	"github.com/Vanshikav123/ByteFlow.git/internal/models"
 	"github.com/Vanshikav123/ByteFlow.git/internal/validator"
 	"github.com/julienschmidt/httprouter"
 )
 
-// Prometheus api increment middleware function
-func (app *application) withMetrics(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		app.apiRequestCounter.WithLabelValues(r.URL.Path, r.Method).Inc() // Increment counter
-		next(w, r)
+// maxQueryLength bounds search queries. Only the first search.MaxTerms words
+// are used anyway.
+const maxQueryLength = 200
+
+// maxContentLength bounds the content of a snippet, in characters. Every
+// revision is stored and diffed against the others, so it's kept well below
+// what a form or JSON body could carry.
+const maxContentLength = 65_536
+
+// snippetSearch shows a page of the snippets matching the q query string
+// parameter, best match first. Without q it shows just the search form.
+func (app *application) snippetSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	page, err := queryInt(r, "page", 1)
+	if err != nil || page < 1 || !validator.MaxChars(query, maxQueryLength) {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Search = &searchPage{Query: query, Page: page}
+	if query != "" {
+		// Ask for one extra result to find out whether there is a next page.
+		results, err := app.search.Search(r.Context(), query, app.authenticatedUserID(r), defaultPageSize+1, (page-1)*defaultPageSize)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		if len(results) > defaultPageSize {
+			results = results[:defaultPageSize]
+			data.Search.HasMore = true
+		}
+		data.Search.Results = results
 	}
+	app.render(w, r, http.StatusOK, "search.html", data)
 }
 
-func (app *application) home(w http.ResponseWriter, r *http.Request) {
-	snippets, err := app.snippets.Latest()
-	if err != nil {
-		app.serverError(w, err)
+func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.loadSnippet(w, r)
+	if !ok {
 		return
 	}
+
 	data := app.newTemplateData(r)
-	data.Snippets = snippets
-	app.render(w, http.StatusOK, "home.html", data)
+	data.Snippet = snippet
+
+	// Unlisted snippets are meant to be found only by those given the link.
+	if snippet.Visibility != models.VisibilityPublic {
+		w.Header().Set("X-Robots-Tag", "noindex")
+	}
+	if app.locked(r, snippet) {
+		w.Header().Set("Cache-Control", "no-store")
+		data.Form = snippetUnlockForm{}
+		app.render(w, r, http.StatusOK, "unlock.html", data)
+		return
+	}
+	// Link previews and crawlers follow links with GET, so a snippet with a
+	// view limit only warns the reader here, and is read with a POST.
+	if app.consumable(r, snippet) {
+		w.Header().Set("Cache-Control", "no-store")
+		app.render(w, r, http.StatusOK, "burn.html", data)
+		return
+	}
+	app.render(w, r, http.StatusOK, "view.html", data)
 }
 
-func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
-	queryparams := httprouter.ParamsFromContext(r.Context())
-	id, err := strconv.Atoi(queryparams.ByName("id"))
+type snippetUnlockForm struct {
+	Password            string `form:"password"`
+	validator.Validator `form:"-"`
+}
 
-	if err != nil || id < 1 {
-		app.notFound(w)
+// snippetUnlockPost checks the password of a snippet and remembers in the
+// session that it was right. Wrong passwords are limited per snippet by
+// app.unlockLimiter, which answers 429 once there have been too many.
+// Every attempt is reserved with the limiter before the password is checked
+// and refunded if it was right.
+func (app *application) snippetUnlockPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.loadSnippet(w, r)
+	if !ok {
+		return
+	}
+	if !app.locked(r, snippet) {
+		http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.Slug), http.StatusSeeOther)
+		return
+	}
+	var form snippetUnlockForm
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	w.Header().Set("Cache-Control", "no-store")
+	if ok, wait := app.unlockLimiter.Reserve(snippet.ID); !ok {
+		minutes := int(wait/time.Minute) + 1
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait/time.Second)+1))
+		form.AddNonFieldError(fmt.Sprintf("Too many wrong passwords have been tried. Try again in %d minutes.", minutes))
+		data.Form = form
+		app.render(w, r, http.StatusTooManyRequests, "unlock.html", data)
+		return
+	}
+	match, err := snippet.PasswordMatches(form.Password)
+	if err != nil {
+		app.unlockLimiter.Refund(snippet.ID)
+		app.serverError(w, r, err)
+		return
+	}
+	if !match {
+		form.AddNonFieldError("The password is incorrect")
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "unlock.html", data)
+		return
+	}
+	app.unlockLimiter.Refund(snippet.ID)
+
+	// Like logging in, unlocking gives the session more than it had.
+	err = app.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
 		return
 	}
-	snippet, err := app.snippets.Get(id)
+	app.sessionManager.Put(r.Context(), unlockedKey(snippet), true)
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.Slug), http.StatusSeeOther)
+}
+
+// snippetRead shows a snippet with a view limit to someone other than its
+// author after the warning of snippetView, using up one of its views.
+func (app *application) snippetRead(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.unlockedSnippet(w, r)
+	if !ok {
+		return
+	}
+	if !app.consumable(r, snippet) {
+		http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.Slug), http.StatusSeeOther)
+		return
+	}
+	snippet, err := app.snippets.Consume(r.Context(), snippet.ID)
 	if err != nil {
+		// Someone else may have had the last view since the snippet was loaded.
 		if errors.Is(err, models.ErrNoRecord) {
 			app.notFound(w)
 		} else {
-			app.serverError(w, err)
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	w.Header().Set("X-Robots-Tag", "noindex")
+	w.Header().Set("Cache-Control", "no-store")
+	app.render(w, r, http.StatusOK, "view.html", data)
+}
+
+func (app *application) snippetHistory(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.unlockedSnippet(w, r)
+	if !ok {
+		return
+	}
+	// The history has the content in it, which would get around the limit.
+	if app.consumable(r, snippet) {
+		app.notFound(w)
+		return
+	}
+	revisions, err := app.revisions.All(r.Context(), snippet.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Revisions = revisions
+	app.render(w, r, http.StatusOK, "history.html", data)
+}
+
+// snippetDiff compares two versions of a snippet given by the from and to
+// query string parameters. Without them it shows the most recent change, and
+// with only to it shows the change that produced that version.
+func (app *application) snippetDiff(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.unlockedSnippet(w, r)
+	if !ok {
+		return
+	}
+	if app.consumable(r, snippet) {
+		app.notFound(w)
+		return
+	}
+	revisions, err := app.revisions.All(r.Context(), snippet.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if len(revisions) == 0 {
+		app.notFound(w)
+		return
+	}
+
+	to, err := queryInt(r, "to", revisions[0].Version)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	from, err := queryInt(r, "from", max(to-1, 1))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	var fromRevision, toRevision *models.Revision
+	for _, rev := range revisions {
+		if rev.Version == from {
+			fromRevision = rev
+		}
+		if rev.Version == to {
+			toRevision = rev
 		}
+	}
+	if fromRevision == nil || toRevision == nil {
+		app.notFound(w)
+		return
+	}
+
+	// Anyone can ask for a diff, so one that would take too long to compute
+	// is replaced by a note rather than tying up the server.
+	hunks, err := diff.Unified(fromRevision.Content, toRevision.Content, 3)
+	if err != nil && !errors.Is(err, diff.ErrTooDifferent) {
+		app.serverError(w, r, err)
 		return
 	}
 
 	data := app.newTemplateData(r)
 	data.Snippet = snippet
+	data.Revisions = revisions
+	data.Diff = &revisionDiff{
+		From:         fromRevision,
+		To:           toRevision,
+		Hunks:        hunks,
+		TooDifferent: err != nil,
+	}
+	app.render(w, r, http.StatusOK, "diff.html", data)
+}
 
-	app.render(w, http.StatusOK, "view.html", data)
+type snippetRestoreForm struct {
+	Version int `form:"version"`
 }
 
+func (app *application) snippetRestorePost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+	var form snippetRestoreForm
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	err = app.snippets.Restore(r.Context(), snippet.ID, app.authenticatedUserID(r), form.Version)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Version %d successfully restored!", form.Version))
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.Slug), http.StatusSeeOther)
+}
+
+// snippetCreateForm is also decoded from the JSON bodies of the snippets API,
+// so that both share the same validation.
+// Tags is a list in JSON, but the HTML forms send a single comma separated
+// field; validate splits both into tags.
 type snippetCreateForm struct {
-	Title               string `form:"title"`
-	Content             string `form:"content"`
-	Expires             int    `form:"expires"`
-	validator.Validator `form:"-"`
+	Title               string            `form:"title" json:"title"`
+	Content             string            `form:"content" json:"content"`
+	Language            string            `form:"language" json:"language"`
+	Tags                []string          `form:"tags" json:"tags"`
+	Visibility          models.Visibility `form:"visibility" json:"visibility"`
+	MaxViews            int               `form:"max_views" json:"max_views"`
+	Password            string            `form:"password" json:"password"`
+	Expires             int               `form:"expires" json:"expires"`
+	validator.Validator `form:"-" json:"-"`
+
+	// protected is set when editing a snippet that has a password, which
+	// can't be changed but still decides the visibility.
+	protected bool
+}
+
+// validate checks the fields shared by the create and edit snippet forms. The
+// tags are normalised first, so a form shown again has them the way they'd
+// be stored. API clients that leave out the visibility get public snippets,
+// as they did before there was a choice, and snippets with a view limit or a
+// password are made unlisted if they'd be public.
+func (form *snippetCreateForm) validate() {
+	form.Tags = models.NormalizeTags(form.Tags)
+	if form.Visibility == "" {
+		form.Visibility = models.VisibilityPublic
+	}
+	restricted := form.MaxViews > 0 || form.Password != "" || form.protected
+	if restricted && form.Visibility == models.VisibilityPublic {
+		form.Visibility = models.VisibilityUnlisted
+	}
+	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long")
+	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Content, maxContentLength), "content", fmt.Sprintf("This field cannot be more than %d characters long", maxContentLength))
+	form.CheckField(models.ValidLanguage(form.Language), "language", "This field must be a known language")
+	form.CheckField(len(form.Tags) <= models.MaxTags, "tags", fmt.Sprintf("A snippet can have at most %d tags", models.MaxTags))
+	for _, tag := range form.Tags {
+		form.CheckField(models.ValidTag(tag), "tags", fmt.Sprintf("%q is not a valid tag: use up to %d lower case letters, digits and + # . -", tag, models.MaxTagLength))
+	}
+	form.CheckField(models.ValidVisibility(form.Visibility), "visibility", "This field must equal public, unlisted or private")
+	form.CheckField(form.MaxViews >= 0 && form.MaxViews <= models.MaxViewLimit, "max_views", fmt.Sprintf("This field must be between 0 and %d", models.MaxViewLimit))
+	if form.Password != "" {
+		form.CheckField(validator.MinChars(form.Password, 8), "password", "This field must be at least 8 characters long")
+		form.CheckField(len(form.Password) <= models.MaxSnippetPasswordLength, "password", fmt.Sprintf("This field cannot be more than %d bytes long", models.MaxSnippetPasswordLength))
+	}
+	form.CheckField(validator.PermittedValue(form.Expires, 1, 7, 365), "expires", "This field must equal 1, 7 or 365")
+}
+
+// fields returns what the form sets on a snippet.
+func (form *snippetCreateForm) fields() models.SnippetFields {
+	return models.SnippetFields{
+		Title:      form.Title,
+		Content:    form.Content,
+		Language:   form.Language,
+		Tags:       form.Tags,
+		Visibility: form.Visibility,
+		MaxViews:   form.MaxViews,
+		Password:   form.Password,
+		Expires:    form.Expires,
+	}
 }
 
 func (app *application) snippetCreate(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
 	data.Form = snippetCreateForm{
-		Expires: 365,
+		Visibility: models.VisibilityPublic,
+		Expires:    365,
 	}
-	app.render(w, http.StatusOK, "create.html", data)
+	app.render(w, r, http.StatusOK, "create.html", data)
 }
 
 func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request) {
@@ -77,23 +370,94 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
-	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long")
-	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
-	form.CheckField(validator.PermittedValue(form.Expires, 1, 7, 365), "expires", "This field must equal 1, 7 or 365")
+	form.validate()
 	if !form.Valid() {
+		// The password isn't shown again, as it would be in the page source.
+		form.Password = ""
 		data := app.newTemplateData(r)
 		data.Form = form
-		app.render(w, http.StatusUnprocessableEntity, "create.html", data)
+		app.render(w, r, http.StatusUnprocessableEntity, "create.html", data)
 		return
 	}
-	id, err := app.snippets.Insert(form.Title, form.Content, form.Expires)
+	_, slug, err := app.snippets.Insert(r.Context(), app.authenticatedUserID(r), form.fields())
 	if err != nil {
-		app.serverError(w, err)
+		app.serverError(w, r, err)
 		return
 	}
+	app.metrics.snippetsCreated.WithLabelValues("web").Inc()
 	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully created!")
-	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", slug), http.StatusSeeOther)
+}
+
+func (app *application) snippetEdit(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Form = snippetCreateForm{
+		Title:      snippet.Title,
+		Content:    snippet.Content,
+		Language:   snippet.Language,
+		Tags:       snippet.Tags,
+		Visibility: snippet.Visibility,
+		MaxViews:   snippet.MaxViews,
+		Expires:    expiresInDays(snippet.Expires),
+	}
+	app.render(w, r, http.StatusOK, "edit.html", data)
+}
+
+func (app *application) snippetEditPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+	var form snippetCreateForm
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	// The view limit and the password can't be changed, but still decide the
+	// visibility.
+	form.MaxViews = snippet.MaxViews
+	form.Password = ""
+	form.protected = snippet.Protected()
+
+	form.validate()
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "edit.html", data)
+		return
+	}
+	err = app.snippets.Update(r.Context(), snippet.ID, app.authenticatedUserID(r), form.fields())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully updated!")
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.Slug), http.StatusSeeOther)
+}
+
+func (app *application) snippetDeletePost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+	err := app.snippets.Delete(r.Context(), snippet.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully deleted!")
+	http.Redirect(w, r, "/user/snippets", http.StatusSeeOther)
 }
 
 type userSignupForm struct {
@@ -106,7 +470,7 @@ type userSignupForm struct {
 func (app *application) userSignup(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
 	data.Form = userSignupForm{}
-	app.render(w, http.StatusOK, "signup.html", data)
+	app.render(w, r, http.StatusOK, "signup.html", data)
 }
 func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
 	var form userSignupForm
@@ -126,19 +490,19 @@ func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
 	if !form.Valid() {
 		data := app.newTemplateData(r)
 		data.Form = form
-		app.render(w, http.StatusUnprocessableEntity, "signup.html", data)
+		app.render(w, r, http.StatusUnprocessableEntity, "signup.html", data)
 		return
 	}
 
-	err = app.users.Insert(form.Name, form.Email, form.Password)
+	err = app.users.Insert(r.Context(), form.Name, form.Email, form.Password)
 	if err != nil {
 		if errors.Is(err, models.ErrDuplicateEmail) {
 			form.AddFieldError("email", "Email address is already in use")
 			data := app.newTemplateData(r)
 			data.Form = form
-			app.render(w, http.StatusUnprocessableEntity, "signup.html", data)
+			app.render(w, r, http.StatusUnprocessableEntity, "signup.html", data)
 		} else {
-			app.serverError(w, err)
+			app.serverError(w, r, err)
 		}
 		return
 	}
@@ -156,7 +520,7 @@ type userLoginForm struct {
 func (app *application) userLogin(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
 	data.Form = userLoginForm{}
-	app.render(w, http.StatusOK, "login.html", data)
+	app.render(w, r, http.StatusOK, "login.html", data)
 }
 func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
 	var form userLoginForm
@@ -171,36 +535,113 @@ func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
 	if !form.Valid() {
 		data := app.newTemplateData(r)
 		data.Form = form
-		app.render(w, http.StatusUnprocessableEntity, "login.html", data)
+		app.render(w, r, http.StatusUnprocessableEntity, "login.html", data)
 		return
 	}
-	id, err := app.users.Authenticate(form.Email, form.Password)
+	id, err := app.users.Authenticate(r.Context(), form.Email, form.Password)
 	if err != nil {
 		if errors.Is(err, models.ErrInvalidCredentials) {
+			app.metrics.logins.WithLabelValues("failure").Inc()
 			form.AddNonFieldError("Email or password is incorrect")
 			data := app.newTemplateData(r)
 			data.Form = form
-			app.render(w, http.StatusUnprocessableEntity, "login.html", data)
+			app.render(w, r, http.StatusUnprocessableEntity, "login.html", data)
 		} else {
-			app.serverError(w, err)
+			app.serverError(w, r, err)
 		}
 		return
 	}
 	err = app.sessionManager.RenewToken(r.Context())
 	if err != nil {
-		app.serverError(w, err)
+		app.serverError(w, r, err)
 		return
 	}
 	app.sessionManager.Put(r.Context(), "authenticatedUserID", id)
+	app.metrics.logins.WithLabelValues("success").Inc()
 	http.Redirect(w, r, "/snippet/create", http.StatusSeeOther)
 }
 func (app *application) userLogoutPost(w http.ResponseWriter, r *http.Request) {
 	err := app.sessionManager.RenewToken(r.Context())
 	if err != nil {
-		app.serverError(w, err)
+		app.serverError(w, r, err)
 		return
 	}
 	app.sessionManager.Remove(r.Context(), "authenticatedUserID")
 	app.sessionManager.Put(r.Context(), "flash", "You've been logged out successfully!")
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
+
+type tokenCreateForm struct {
+	Name                string `form:"name"`
+	Expires             int    `form:"expires"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) userTokens(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = tokenCreateForm{Expires: 90}
+	app.renderTokens(w, r, http.StatusOK, data)
+}
+
+// renderTokens adds the user's tokens and any just-created token to data and
+// renders the tokens page.
+func (app *application) renderTokens(w http.ResponseWriter, r *http.Request, status int, data *templateData) {
+	tokens, err := app.tokens.All(r.Context(), app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	data.Tokens = tokens
+	// The plaintext of a new token is kept in the session only until the
+	// redirect after creating it has been followed.
+	data.NewToken = app.sessionManager.PopString(r.Context(), "newToken")
+	app.render(w, r, status, "tokens.html", data)
+}
+
+func (app *application) userTokensPost(w http.ResponseWriter, r *http.Request) {
+	var form tokenCreateForm
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Name, 100), "name", "This field cannot be more than 100 characters long")
+	form.CheckField(validator.PermittedValue(form.Expires, 0, 7, 30, 90, 365), "expires", "This field must equal 0, 7, 30, 90 or 365")
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.renderTokens(w, r, http.StatusUnprocessableEntity, data)
+		return
+	}
+
+	token, err := app.tokens.Insert(r.Context(), app.authenticatedUserID(r), form.Name, form.Expires)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.sessionManager.Put(r.Context(), "newToken", token)
+	app.sessionManager.Put(r.Context(), "flash", "Token created. Copy it now, it won't be shown again!")
+	http.Redirect(w, r, "/user/tokens", http.StatusSeeOther)
+}
+
+func (app *application) userTokenRevokePost(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+	err = app.tokens.Delete(r.Context(), id, app.authenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "Token revoked.")
+	http.Redirect(w, r, "/user/tokens", http.StatusSeeOther)
+}
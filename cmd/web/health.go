@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+// readinessTimeout bounds how long /readyz waits on any one dependency.
+const readinessTimeout = 2 * time.Second
+
+// checkResult is the outcome of one readiness check as reported by the admin
+// listener's /readyz.
+type checkResult struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// healthz is the liveness probe. It deliberately checks nothing beyond the
+// process answering HTTP, so a database outage doesn't get the process
+// restarted.
+func (app *application) healthz(w http.ResponseWriter, r *http.Request) {
+	app.writeJSON(w, http.StatusOK, envelope{"status": "ok"}, nil)
+}
+
+/*
+readyz is the readiness probe of the public router. It answers 200 only if every check passes and
+503 otherwise, with no more than whether each check passed:
+
+	{"status": "unavailable", "checks": {"database": "ok", "sessions": "failing", ...}}
+
+Anyone can reach it, so the errors, which can name hosts, addresses and drivers, and the
+latencies are left to the logs and to adminReadyz.
+
+It starts failing as soon as graceful shutdown begins, so the orchestrator stops routing new
+requests here while the in-flight ones drain.
+*/
+func (app *application) readyz(w http.ResponseWriter, r *http.Request) {
+	status, results := app.checkReadiness(r.Context())
+	checks := make(map[string]string, len(results))
+	for name, result := range results {
+		checks[name] = result.Status
+	}
+	app.writeJSON(w, status, envelope{"status": readinessStatus(status), "checks": checks}, http.Header{"Cache-Control": {"no-store"}})
+}
+
+/*
+adminReadyz is readyz for the admin listener. It lists each check with its status, how long it
+took and, if it failed, why:
+
+	{"status": "unavailable", "checks": {"database": {"status": "failing", "latency_ms": 2000.3, "error": "..."}, ...}}
+*/
+func (app *application) adminReadyz(w http.ResponseWriter, r *http.Request) {
+	status, results := app.checkReadiness(r.Context())
+	app.writeJSON(w, status, envelope{"status": readinessStatus(status), "checks": results}, http.Header{"Cache-Control": {"no-store"}})
+}
+
+// checkReadiness runs every readiness check and returns the status code to
+// answer with and the result of each. Failing checks are logged.
+func (app *application) checkReadiness(ctx context.Context) (int, map[string]checkResult) {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	checks := map[string]func(context.Context) error{
+		"database":  app.checkDatabase,
+		"sessions":  app.checkSessions,
+		"templates": app.checkTemplates,
+		"shutdown":  app.checkShutdown,
+	}
+
+	status := http.StatusOK
+	results := make(map[string]checkResult, len(checks))
+	for name, check := range checks {
+		start := time.Now()
+		err := check(ctx)
+		result := checkResult{
+			Status:    "ok",
+			LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if err != nil {
+			app.logger.Warn("readiness check failing", "check", name, "error", err)
+			result.Status = "failing"
+			result.Error = err.Error()
+			status = http.StatusServiceUnavailable
+		}
+		results[name] = result
+	}
+	return status, results
+}
+
+func readinessStatus(status int) string {
+	if status != http.StatusOK {
+		return "unavailable"
+	}
+	return "ok"
+}
+
+// checkDatabase pings the connection pool. The in-memory backend has no
+// pool and is always reachable.
+func (app *application) checkDatabase(ctx context.Context) error {
+	if app.db == nil {
+		return nil
+	}
+	return app.db.PingContext(ctx)
+}
+
+// checkSessions looks up a token that can't exist, which reaches the session
+// store without creating anything in it.
+func (app *application) checkSessions(ctx context.Context) error {
+	const probe = "readiness-probe"
+	var err error
+	if store, ok := app.sessionManager.Store.(scs.CtxStore); ok {
+		_, _, err = store.FindCtx(ctx, probe)
+	} else {
+		_, _, err = app.sessionManager.Store.Find(probe)
+	}
+	return err
+}
+
+func (app *application) checkTemplates(ctx context.Context) error {
+	if len(app.templateCache) == 0 {
+		return errors.New("template cache is empty")
+	}
+	return nil
+}
+
+func (app *application) checkShutdown(ctx context.Context) error {
+	if app.shuttingDown.Load() {
+		return errors.New("server is shutting down")
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// The public /readyz says which checks fail but not why, the admin one does.
+func TestReadyzOnlyExplainsOnTheAdminListener(t *testing.T) {
+	app := newTestApplication(t)
+	app.shuttingDown.Store(true)
+
+	tests := []struct {
+		name      string
+		handler   http.Handler
+		wantError bool
+	}{
+		{"public", app.routes(), false},
+		{"admin", http.HandlerFunc(app.adminReadyz), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if w.Code != http.StatusServiceUnavailable {
+				t.Errorf("got status %d, want %d", w.Code, http.StatusServiceUnavailable)
+			}
+			body := w.Body.String()
+			if got := strings.Contains(body, "server is shutting down"); got != tt.wantError {
+				t.Errorf("error shown is %v, want %v: %s", got, tt.wantError, body)
+			}
+
+			var resp struct {
+				Status string                     `json:"status"`
+				Checks map[string]json.RawMessage `json:"checks"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Status != "unavailable" || len(resp.Checks) != 4 {
+				t.Errorf("got %s", body)
+			}
+			if !tt.wantError && (string(resp.Checks["shutdown"]) != `"failing"` || string(resp.Checks["database"]) != `"ok"`) {
+				t.Errorf("got checks %s", body)
+			}
+		})
+	}
+}
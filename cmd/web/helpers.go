@@ -2,12 +2,22 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"mime"
 	"net/http"
 	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
 	"github.com/go-playground/form/v4"
+	"github.com/julienschmidt/httprouter"
+	"go.opentelemetry.io/otel/trace"
 )
 
 /*
@@ -15,14 +25,38 @@ Logging the Error: It logs the error details, including a stack trace, to help d
 debug.Stack(): This retrieves the current call stack as a byte slice.
 
 	The stack trace helps developers identify where the error occurred in the code.
-	Writes the error trace (error message + stack trace) to the log. This helps developers diagnose the issue later.
+	The log line carries the request ID, which is also sent back in the X-Request-ID header, so a 500
+	someone reports can be matched to its trace.
 */
-func (app *application) serverError(w http.ResponseWriter, err error) {
-	trace := fmt.Sprintf("%s\n%s", err.Error(), debug.Stack())
-	app.errorLog.Print(trace)
+func (app *application) serverError(w http.ResponseWriter, r *http.Request, err error) {
+	app.logServerError(r, err)
 	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 }
 
+// logServerError logs err with the current stack trace for serverError and
+// apiServerError.
+func (app *application) logServerError(r *http.Request, err error) {
+	app.requestLogger(r).Error("server error",
+		"method", r.Method,
+		"uri", loggedURI(r),
+		"error", err.Error(),
+		"trace", string(debug.Stack()),
+	)
+}
+
+// requestLogger returns the application logger with the request ID, and the
+// trace ID when the request is being traced, attached.
+func (app *application) requestLogger(r *http.Request) *slog.Logger {
+	logger := app.logger
+	if id, ok := r.Context().Value(requestIDContextKey).(string); ok {
+		logger = logger.With("request_id", id)
+	}
+	if sc := trace.SpanContextFromContext(r.Context()); sc.IsSampled() {
+		logger = logger.With("trace_id", sc.TraceID().String())
+	}
+	return logger
+}
+
 func (app *application) clientError(w http.ResponseWriter, status int) {
 	http.Error(w, http.StatusText(status), status)
 }
@@ -31,21 +65,25 @@ func (app *application) notFound(w http.ResponseWriter) {
 	app.clientError(w, http.StatusNotFound)
 }
 
-func (app *application) render(w http.ResponseWriter, status int, page string, data *templateData) {
+func (app *application) render(w http.ResponseWriter, r *http.Request, status int, page string, data *templateData) {
 	ts, ok := app.templateCache[page]
 
 	if !ok {
 		err := fmt.Errorf("the template %s does not exist", page)
-		app.serverError(w, err)
+		app.serverError(w, r, err)
 		return
 	}
 	/*A new buffer (bytes.Buffer) is created to temporarily store the rendered HTML.
 	This is done to avoid writing incomplete or erroneous HTML directly to the response.*/
 	buf := new(bytes.Buffer)
 
+	span := renderSpan(r.Context(), page)
+	start := time.Now()
 	err := ts.ExecuteTemplate(buf, "base", data)
+	app.metrics.renderDuration.WithLabelValues(page).Observe(time.Since(start).Seconds())
+	span.End()
 	if err != nil {
-		app.serverError(w, err)
+		app.serverError(w, r, err)
 		return
 	}
 
@@ -92,3 +130,226 @@ func (app *application) isAuthenticated(r *http.Request) bool {
 	}
 	return isAuthenticated
 }
+
+// authenticatedUserID returns the id of the logged in user, or 0 if the
+// request is not authenticated.
+func (app *application) authenticatedUserID(r *http.Request) int {
+	id, ok := r.Context().Value(authenticatedUserIDContextKey).(int)
+	if !ok {
+		return 0
+	}
+	return id
+}
+
+// snippetSlugParam returns the :slug route parameter.
+func snippetSlugParam(r *http.Request) string {
+	return httprouter.ParamsFromContext(r.Context()).ByName("slug")
+}
+
+// loadSnippet fetches the snippet named by the :slug route parameter, sending a
+// 404 and returning ok == false if there is no such live snippet or it's
+// private to someone else.
+//
+// Pages of snippets used to be addressed by their id, and links to them are
+// still around, so a GET with a number instead of a slug is redirected for
+// good to the same page of the legacy snippet with that id, if it's public.
+func (app *application) loadSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	slug := snippetSlugParam(r)
+	if id, err := strconv.Atoi(slug); err == nil && id >= 1 && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+		newSlug, err := app.snippets.LegacySlug(r.Context(), id)
+		if err == nil {
+			u := *r.URL
+			u.Path = strings.Replace(u.Path, "/"+slug, "/"+newSlug, 1)
+			u.RawPath = ""
+			http.Redirect(w, r, u.RequestURI(), http.StatusMovedPermanently)
+			return nil, false
+		}
+		// A slug can happen to be all digits, so carry on looking it up.
+		if !errors.Is(err, models.ErrNoRecord) {
+			app.serverError(w, r, err)
+			return nil, false
+		}
+	}
+	snippet, err := app.snippets.GetBySlug(r.Context(), slug)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return nil, false
+	}
+	// Someone else's private snippet is answered as if it didn't exist, so
+	// its slug gives nothing away.
+	if !snippet.VisibleTo(app.authenticatedUserID(r)) {
+		app.notFound(w)
+		return nil, false
+	}
+	return snippet, true
+}
+
+// ownedSnippet is like loadSnippet but also checks that the snippet belongs to
+// the logged in user, sending a 403 if it belongs to someone else.
+func (app *application) ownedSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	snippet, ok := app.loadSnippet(w, r)
+	if !ok {
+		return nil, false
+	}
+	if snippet.UserID != app.authenticatedUserID(r) {
+		app.clientError(w, http.StatusForbidden)
+		return nil, false
+	}
+	return snippet, true
+}
+
+// consumable reports whether showing the snippet to the user of r uses up one
+// of its views. Its author can see it as often as they like.
+func (app *application) consumable(r *http.Request, s *models.Snippet) bool {
+	return s.Limited() && s.UserID != app.authenticatedUserID(r)
+}
+
+// unlockedKey is the session key remembering that the password of a snippet
+// has been given.
+func unlockedKey(s *models.Snippet) string {
+	return fmt.Sprintf("unlockedSnippet:%d", s.ID)
+}
+
+// locked reports whether the user of r still has to give the password of the
+// snippet in this session. Its author never does.
+func (app *application) locked(r *http.Request, s *models.Snippet) bool {
+	return s.Protected() && s.UserID != app.authenticatedUserID(r) &&
+		!app.sessionManager.GetBool(r.Context(), unlockedKey(s))
+}
+
+// unlockedSnippet is like loadSnippet, but redirects to the snippet's page,
+// where the password is asked for, if it's still locked.
+func (app *application) unlockedSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	snippet, ok := app.loadSnippet(w, r)
+	if !ok {
+		return nil, false
+	}
+	if app.locked(r, snippet) {
+		http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.Slug), http.StatusSeeOther)
+		return nil, false
+	}
+	return snippet, true
+}
+
+// expiresInDays maps the time left before an expiry onto the closest of the
+// permitted form values, so editing a snippet never silently extends its life.
+func expiresInDays(expires time.Time) int {
+	switch remaining := time.Until(expires); {
+	case remaining <= 24*time.Hour:
+		return 1
+	case remaining <= 7*24*time.Hour:
+		return 7
+	default:
+		return 365
+	}
+}
+
+// queryInt reads an integer from the URL query string, returning def if the
+// parameter is absent.
+func queryInt(r *http.Request, key string, def int) (int, error) {
+	s := r.URL.Query().Get(key)
+	if s == "" {
+		return def, nil
+	}
+	return strconv.Atoi(s)
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>"
+// header. ok is false if the request has no such header.
+func bearerToken(r *http.Request) (token string, ok bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// hasBearerToken reports whether the request carries an API token at all,
+// valid or not.
+func hasBearerToken(r *http.Request) bool {
+	_, ok := bearerToken(r)
+	return ok
+}
+
+// envelope wraps JSON responses so that every body is an object with a named
+// top-level key, e.g. {"snippet": {...}} or {"error": {...}}.
+type envelope map[string]any
+
+// writeJSON encodes data and sends it with the given status. Like render, the
+// body is encoded up front so an encoding failure can still become a 500.
+func (app *application) writeJSON(w http.ResponseWriter, status int, data envelope, headers http.Header) error {
+	js, err := json.MarshalIndent(data, "", "\t")
+	if err != nil {
+		return err
+	}
+	js = append(js, '\n')
+
+	for key, value := range headers {
+		w.Header()[key] = value
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(js)
+	return nil
+}
+
+// maxJSONBytes caps the size of API request bodies.
+const maxJSONBytes = 1_048_576
+
+// readJSON decodes a single JSON object from the request body into dst. The
+// errors it returns are safe to show to API clients.
+func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || mediaType != "application/json" {
+			return errors.New("body must be sent with Content-Type: application/json")
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxJSONBytes)
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		var unmarshalTypeError *json.UnmarshalTypeError
+		var maxBytesError *http.MaxBytesError
+		var invalidUnmarshalError *json.InvalidUnmarshalError
+
+		switch {
+		case errors.As(err, &syntaxError):
+			return fmt.Errorf("body contains badly-formed JSON (at character %d)", syntaxError.Offset)
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return errors.New("body contains badly-formed JSON")
+		case errors.As(err, &unmarshalTypeError):
+			if unmarshalTypeError.Field != "" {
+				return fmt.Errorf("body contains incorrect JSON type for field %q", unmarshalTypeError.Field)
+			}
+			return fmt.Errorf("body contains incorrect JSON type (at character %d)", unmarshalTypeError.Offset)
+		case errors.Is(err, io.EOF):
+			return errors.New("body must not be empty")
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
+			return fmt.Errorf("body contains unknown key %s", fieldName)
+		case errors.As(err, &maxBytesError):
+			return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+		case errors.As(err, &invalidUnmarshalError):
+			// A programming mistake rather than bad input, same as the
+			// InvalidDecoderError case in decodePostForm.
+			panic(err)
+		default:
+			return err
+		}
+	}
+
+	err = dec.Decode(&struct{}{})
+	if !errors.Is(err, io.EOF) {
+		return errors.New("body must only contain a single JSON value")
+	}
+	return nil
+}
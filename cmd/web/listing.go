@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+	"github.com/Vanshikav123/ByteFlow.git/internal/validator"
+)
+
+/*
+listingForm is the query string of a snippet listing. The home page, the user's own snippets, the
+API and the feed all read their filters with parseListing and hand the resulting
+models.ListQuery to SnippetModel.Find, so a filter works the same way everywhere.
+
+	?author=3&tag=go&language=go&from=2025-01-01&to=2025-01-31&sort=title&cursor=...
+
+The dates are days in UTC and both are inclusive. The cursor is the next_cursor of the page
+before, and is only valid with the same sort order.
+*/
+type listingForm struct {
+	Author   int
+	Tag      string
+	Language string
+	From     string
+	To       string
+	Sort     string
+	Cursor   string
+	validator.Validator
+
+	from, to time.Time
+}
+
+// dateLayout is the format of the from and to parameters, the one date inputs
+// submit.
+const dateLayout = "2006-01-02"
+
+func parseListing(r *http.Request) *listingForm {
+	q := r.URL.Query()
+	form := &listingForm{
+		Tag:      strings.ToLower(strings.TrimSpace(q.Get("tag"))),
+		Language: q.Get("language"),
+		From:     q.Get("from"),
+		To:       q.Get("to"),
+		Sort:     q.Get("sort"),
+		Cursor:   q.Get("cursor"),
+	}
+
+	if s := q.Get("author"); s != "" {
+		id, err := strconv.Atoi(s)
+		form.CheckField(err == nil && id >= 1, "author", "This field must be a positive integer")
+		form.Author = id
+	}
+	form.CheckField(form.Tag == "" || models.ValidTag(form.Tag), "tag", "This field must be a valid tag")
+	form.CheckField(models.ValidLanguage(form.Language), "language", "This field must be a known language")
+	form.CheckField(models.ValidSortOrder(models.SortOrder(form.Sort)), "sort", "This field must equal newest, oldest or title")
+
+	var err error
+	if form.From != "" {
+		form.from, err = time.Parse(dateLayout, form.From)
+		form.CheckField(err == nil, "from", "This field must be a date like 2006-01-02")
+	}
+	if form.To != "" {
+		form.to, err = time.Parse(dateLayout, form.To)
+		form.CheckField(err == nil, "to", "This field must be a date like 2006-01-02")
+		// The query wants the first moment not included, the day after.
+		form.to = form.to.AddDate(0, 0, 1)
+	}
+	if form.Valid() && !form.from.IsZero() && !form.to.IsZero() {
+		form.CheckField(form.from.Before(form.to), "to", "This field cannot be before from")
+	}
+	return form
+}
+
+// query returns the models.ListQuery for the form, for pages of limit snippets
+// seen by the user viewerID.
+func (form *listingForm) query(viewerID, limit int) models.ListQuery {
+	return models.ListQuery{
+		ViewerID: viewerID,
+		AuthorID: form.Author,
+		Tag:      form.Tag,
+		Language: form.Language,
+		From:     form.from,
+		To:       form.to,
+		Sort:     models.SortOrder(form.Sort),
+		Limit:    limit,
+		After:    form.Cursor,
+	}
+}
+
+// values returns the filters and sort order as query string parameters,
+// leaving out the ones that aren't set and the cursor.
+func (form *listingForm) values() url.Values {
+	v := url.Values{}
+	if form.Author != 0 {
+		v.Set("author", strconv.Itoa(form.Author))
+	}
+	for key, value := range map[string]string{
+		"tag": form.Tag, "language": form.Language, "from": form.From, "to": form.To, "sort": form.Sort,
+	} {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+	return v
+}
+
+// url returns path with the filters and sort order as its query string.
+func (form *listingForm) url(path string) string {
+	if v := form.values(); len(v) > 0 {
+		return path + "?" + v.Encode()
+	}
+	return path
+}
+
+// Filtered reports whether any filter is set, so templates can offer to
+// clear them.
+func (form *listingForm) Filtered() bool {
+	return form.Author != 0 || form.Tag != "" || form.Language != "" || form.From != "" || form.To != ""
+}
+
+// listingPage is what the listing templates need besides the snippets: the
+// form to show the filters in, and where the next page is.
+type listingPage struct {
+	Path string // the listing's own path, where the filter form submits to
+	Form *listingForm
+	Next string // cursor of the next page, "" on the last
+}
+
+// NextURL is the URL of the next page, with the same filters.
+func (p *listingPage) NextURL() string {
+	v := p.Form.values()
+	v.Set("cursor", p.Next)
+	return p.Path + "?" + v.Encode()
+}
+
+// FeedURL is the URL of the Atom feed of the listing.
+func (p *listingPage) FeedURL() string {
+	return p.Form.url("/feed.atom")
+}
+
+// listSnippets parses the listing parameters of r and finds the page of
+// snippets they ask for. Invalid parameters, including a cursor that can't be
+// used, answer 400; ok is false if a response has been sent.
+func (app *application) listSnippets(w http.ResponseWriter, r *http.Request, authorID int) (*models.SnippetPage, *listingForm, bool) {
+	form := parseListing(r)
+	if authorID != 0 {
+		form.Author = authorID
+	}
+	if !form.Valid() {
+		app.clientError(w, http.StatusBadRequest)
+		return nil, nil, false
+	}
+	page, err := app.snippets.Find(r.Context(), form.query(app.authenticatedUserID(r), app.config.LatestLimit))
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			app.clientError(w, http.StatusBadRequest)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return nil, nil, false
+	}
+	return page, form, true
+}
+
+// home lists the live snippets, newest first unless another order is asked
+// for, a page at a time.
+func (app *application) home(w http.ResponseWriter, r *http.Request) {
+	page, form, ok := app.listSnippets(w, r, 0)
+	if !ok {
+		return
+	}
+	data := app.newTemplateData(r)
+	data.Snippets = page.Snippets
+	data.Listing = &listingPage{Path: "/", Form: form, Next: page.Next}
+	app.render(w, r, http.StatusOK, "home.html", data)
+}
+
+// userSnippets is the home page restricted to the snippets of the user.
+func (app *application) userSnippets(w http.ResponseWriter, r *http.Request) {
+	page, form, ok := app.listSnippets(w, r, app.authenticatedUserID(r))
+	if !ok {
+		return
+	}
+	data := app.newTemplateData(r)
+	data.Snippets = page.Snippets
+	// The author is implied by the path, so it's left out of the links.
+	form.Author = 0
+	data.Listing = &listingPage{Path: "/user/snippets", Form: form, Next: page.Next}
+	app.render(w, r, http.StatusOK, "snippets.html", data)
+}
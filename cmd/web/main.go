@@ -1,106 +1,253 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"database/sql"
 	"flag"
-	"log"
+	"html/template"
+	"log/slog"
 	"net/http"
 	"os"
-	"text/template" // New import
+	"sync/atomic"
 	"time"
 
 	"github.com/Vanshikav123/ByteFlow.git/internal/models"
-	"github.com/alexedwards/scs/mysqlstore"
+	"github.com/XSAM/otelsql"
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-playground/form/v4"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 type application struct {
-	// log defination
-	errorLog *log.Logger
-	infoLog  *log.Logger
+	// logger is shared by every part of the app; request-scoped lines go
+	// through app.requestLogger so they carry the request ID.
+	logger *slog.Logger
 	//
-	snippets          *models.SnippetModel
-	users             *models.UserModel
-	templateCache     map[string]*template.Template
-	formDecoder       *form.Decoder
-	apiRequestCounter *prometheus.CounterVec
-	sessionManager    *scs.SessionManager
+	config         config
+	db             *sql.DB // nil for the in-memory backend
+	shuttingDown   atomic.Bool
+	snippets       models.SnippetModelInterface
+	users          models.UserModelInterface
+	revisions      models.RevisionModelInterface
+	tokens         models.TokenModelInterface
+	search         models.SearchModelInterface
+	templateCache  map[string]*template.Template
+	formDecoder    *form.Decoder
+	metrics        *metrics
+	sessionManager *scs.SessionManager
+	unlockLimiter  *failureLimiter // wrong snippet passwords, by snippet id
 }
 
 func main() {
-	addr := flag.String("addr", ":4000", "http network address")
-	dsn := flag.String("dsn", "web:pass@/snippetbox?parseTime=true", "MySQL data source name")
+	// Until the config is loaded we don't know which format to log in, so
+	// errors reading it are logged as plain text.
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
 
-	flag.Parse()
-	// log.New taking three parameters first is io.writer which is stdout and stderr to log info and error respectively and shortfile for file name and line number
-	// stdout for standard info log
-	// stderr for error
-	infoLog := log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
-	errorLog := log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile)
+	cfg, err := loadConfig()
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+	logger = cfg.newLogger(os.Stdout)
+	slog.SetDefault(logger)
+
+	// Tracing goes first so the database connections opened next are traced.
+	shutdownTracing, err := setupTracing(&cfg)
+	if err != nil {
+		logger.Error("setting up tracing", "exporter", cfg.Tracing.Exporter, "error", err)
+		os.Exit(1)
+	}
 
-	db, err := openDB(*dsn)
+	stores, err := openStores(cfg.DB.Driver, cfg.DB.DSN, cfg.BcryptCost)
 	if err != nil {
-		errorLog.Fatal(err)
+		logger.Error("opening stores", "driver", cfg.DB.Driver, "error", err)
+		os.Exit(1)
+	}
+	stores.trace()
+
+	// Subcommands run instead of the server.
+	if flag.Arg(0) == "migrate" {
+		err = runMigrate(stores, flag.Args()[1:], os.Stdout)
+		stores.Close()
+		if err != nil {
+			logger.Error("migrate", "error", err)
+			os.Exit(1)
+		}
+		return
 	}
-	defer db.Close()
 
 	templateCache, err := newTemplateCache()
 	if err != nil {
-		errorLog.Fatal(err)
+		logger.Error("loading templates", "error", err)
+		os.Exit(1)
 	}
 	formDecoder := form.NewDecoder()
 
-	sessionManager := scs.New()
-	sessionManager.Store = mysqlstore.New(db)
-	sessionManager.Lifetime = 12 * time.Hour
-
 	// Initialize Prometheus metrics
-	apiRequestCounter := prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "gosnippet_api_requests_total",
-			Help: "Total number of API requests processed by GoSnippet.",
-		},
-		[]string{"endpoint", "method"},
-	)
-	prometheus.MustRegister(apiRequestCounter)
+	metrics := newMetrics(prometheus.DefaultRegisterer)
+	if stores.db != nil {
+		metrics.registerDB(prometheus.DefaultRegisterer, stores.db, cfg.DB.Driver)
+	}
+
+	sessionManager := scs.New()
+	sessionManager.Store = stores.sessions
+	sessionManager.Lifetime = time.Duration(cfg.Session.Lifetime)
 
 	app := &application{
-		errorLog:          errorLog,
-		infoLog:           infoLog,
-		snippets:          &models.SnippetModel{DB: db},
-		users:             &models.UserModel{DB: db},
-		templateCache:     templateCache,
-		formDecoder:       formDecoder,
-		apiRequestCounter: apiRequestCounter, // Attach the counter
-		sessionManager:    sessionManager,
+		logger:         logger,
+		config:         cfg,
+		db:             stores.db,
+		snippets:       stores.snippets,
+		users:          stores.users,
+		revisions:      stores.revisions,
+		tokens:         stores.tokens,
+		search:         stores.search,
+		templateCache:  templateCache,
+		formDecoder:    formDecoder,
+		metrics:        metrics,
+		sessionManager: sessionManager,
+		unlockLimiter:  newFailureLimiter(unlockMaxFailures, unlockWindow),
 	}
 
+	// scs calls ErrorFunc when it fails to load or save a session.
+	sessionManager.ErrorFunc = func(w http.ResponseWriter, r *http.Request, err error) {
+		metrics.sessionErrors.Inc()
+		app.serverError(w, r, err)
+	}
+
+	reaper := newReaper(stores.snippets, time.Duration(cfg.Reaper.Interval), cfg.Reaper.Batch, logger)
+	reaper.Start()
+
 	tlsConfig := &tls.Config{
 		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
 	}
 
+	// Certificates come either from an ACME server or from files that are
+	// reloaded when they change.
+	var acmeManager *autocert.Manager
+	var certs *certReloader
+	if cfg.TLS.ACME.Enabled {
+		acmeManager, err = newACMEManager(&cfg)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		tlsConfig.GetCertificate = acmeManager.GetCertificate
+		tlsConfig.NextProtos = []string{"h2", "http/1.1", acme.ALPNProto}
+		logger.Info("using ACME certificates", "domains", cfg.TLS.ACME.Domains.String(), "directory", cfg.TLS.ACME.DirectoryURL)
+	} else {
+		certs, err = newCertReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile, time.Duration(cfg.TLS.ReloadInterval), logger)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		tlsConfig.GetCertificate = certs.GetCertificate
+		certs.Start()
+	}
+
 	srv := &http.Server{
-		Addr:      *addr,
-		ErrorLog:  errorLog,
+		Addr: cfg.Addr,
+		// net/http reports things like TLS handshake failures through a
+		// *log.Logger, so bridge it onto the structured logger.
+		ErrorLog:  slog.NewLogLogger(logger.Handler(), slog.LevelError),
 		Handler:   app.routes(),
 		TLSConfig: tlsConfig,
 
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		IdleTimeout:  time.Duration(cfg.Timeouts.Idle),
+		ReadTimeout:  time.Duration(cfg.Timeouts.Read),
+		WriteTimeout: time.Duration(cfg.Timeouts.Write),
 	}
 
-	infoLog.Printf("Starting server on %s", *addr)
-	err = srv.ListenAndServeTLS("./tls/cert.pem", "./tls/key.pem")
-	errorLog.Fatal(err)
+	// The admin and redirect listeners are plain HTTP; serve runs them
+	// alongside srv.
+	var plain []*http.Server
+	if cfg.Admin.Addr != "" {
+		if !isLoopback(cfg.Admin.Addr) && cfg.Admin.Username == "" && cfg.Admin.Token == "" {
+			logger.Warn("admin listener is not on a loopback address and has no authentication configured", "addr", cfg.Admin.Addr)
+		}
+		logger.Info("starting admin server", "addr", cfg.Admin.Addr)
+		plain = append(plain, &http.Server{
+			Addr:     cfg.Admin.Addr,
+			ErrorLog: srv.ErrorLog,
+			Handler:  app.adminRoutes(),
+
+			IdleTimeout: time.Duration(cfg.Timeouts.Idle),
+			ReadTimeout: time.Duration(cfg.Timeouts.Read),
+			// No write timeout: pprof profiles and traces stream for as long
+			// as the client asks them to.
+		})
+	}
+	if cfg.TLS.RedirectAddr != "" {
+		logger.Info("starting redirect server", "addr", cfg.TLS.RedirectAddr)
+		plain = append(plain, &http.Server{
+			Addr:     cfg.TLS.RedirectAddr,
+			ErrorLog: srv.ErrorLog,
+			Handler:  app.redirectRoutes(acmeManager),
+
+			IdleTimeout:  time.Duration(cfg.Timeouts.Idle),
+			ReadTimeout:  time.Duration(cfg.Timeouts.Read),
+			WriteTimeout: time.Duration(cfg.Timeouts.Write),
+		})
+	}
+
+	logger.Info("starting server", "addr", cfg.Addr, "driver", cfg.DB.Driver)
+	err = app.serve(srv, plain, time.Duration(cfg.ShutdownDelay), time.Duration(cfg.ShutdownTimeout))
+	if err != nil {
+		logger.Error("server", "error", err)
+	}
+
+	// Shut down the rest in the reverse order it was started. Sessions are
+	// written by LoadAndSave at the end of each request, so once the server
+	// has drained only the store's cleanup goroutine is left to stop.
+	if certs != nil {
+		logger.Info("stopping certificate reloader")
+		certs.Stop()
+	}
+	logger.Info("stopping reaper")
+	reaper.Stop()
+	logger.Info("stopping session store cleanup")
+	stores.StopCleanup()
+	logger.Info("closing database")
+	if closeErr := stores.Close(); closeErr != nil {
+		logger.Error("closing database", "error", closeErr)
+	}
+	logger.Info("flushing traces")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if traceErr := shutdownTracing(ctx); traceErr != nil {
+		logger.Error("flushing traces", "error", traceErr)
+	}
+	cancel()
+	logger.Info("server stopped")
+
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// dbSystems names each database/sql driver for the db.system span attribute.
+var dbSystems = map[string]attribute.KeyValue{
+	"mysql":  semconv.DBSystemMySQL,
+	"pgx":    semconv.DBSystemPostgreSQL,
+	"sqlite": semconv.DBSystemSqlite,
 }
 
-func openDB(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("mysql", dsn)
+// openDB opens and checks a connection pool. Every statement run through it,
+// including the session stores', gets a tracing span with the SQL attached.
+func openDB(driver, dsn string) (*sql.DB, error) {
+	db, err := otelsql.Open(driver, dsn,
+		otelsql.WithAttributes(dbSystems[driver]),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+			DisableErrSkip:       true,
+		}),
+	)
 	if err != nil {
 		return nil, err
 	}
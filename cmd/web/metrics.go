@@ -0,0 +1,97 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+/*
+metrics holds the application's Prometheus collectors. Requests are labelled by the route pattern
+they matched ("/snippet/view/:slug"), never by the raw path, so the number of series stays fixed no
+matter how many snippets there are.
+*/
+type metrics struct {
+	// apiRequests is the original request counter, kept so existing
+	// dashboards keep working. requests supersedes it.
+	apiRequests     *prometheus.CounterVec
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	renderDuration  *prometheus.HistogramVec
+	logins          *prometheus.CounterVec
+	snippetsCreated *prometheus.CounterVec
+	sessionErrors   prometheus.Counter
+}
+
+func newMetrics(reg prometheus.Registerer) *metrics {
+	m := &metrics{
+		apiRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gosnippet_api_requests_total",
+			Help: "Total number of API requests processed by GoSnippet.",
+		}, []string{"endpoint", "method"}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gosnippet_http_requests_total",
+			Help: "Total number of HTTP requests by route, method and response status.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "gosnippet_http_request_duration_seconds",
+			Help:    "Time taken to serve HTTP requests, including middleware.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		renderDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "gosnippet_template_render_duration_seconds",
+			Help:    "Time taken to execute page templates.",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1},
+		}, []string{"page"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gosnippet_logins_total",
+			Help: "Total number of login attempts by result (success or failure).",
+		}, []string{"result"}),
+		snippetsCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gosnippet_snippets_created_total",
+			Help: "Total number of snippets created, by source (web or api).",
+		}, []string{"source"}),
+		sessionErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "gosnippet_session_errors_total",
+			Help: "Total number of errors loading or saving sessions.",
+		}),
+	}
+	reg.MustRegister(m.apiRequests, m.requests, m.requestDuration, m.renderDuration,
+		m.logins, m.snippetsCreated, m.sessionErrors)
+
+	// Start the labelled series at zero so rates work before the first event.
+	m.logins.WithLabelValues("success")
+	m.logins.WithLabelValues("failure")
+	m.snippetsCreated.WithLabelValues("web")
+	m.snippetsCreated.WithLabelValues("api")
+	return m
+}
+
+// registerDB exports the connection pool statistics of db (open, in use and
+// idle connections, waits and so on) under the go_sql_ prefix.
+func (m *metrics) registerDB(reg prometheus.Registerer, db *sql.DB, driver string) {
+	reg.MustRegister(collectors.NewDBStatsCollector(db, driver))
+}
+
+// instrument records the count, status and latency of requests to the route
+// registered under pattern. It wraps the whole middleware chain of the route,
+// so session loading and authentication are part of the latency. The pattern
+// also names the trace span and goes in the request's log line.
+func (app *application) instrument(pattern string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		setRoute(r.Context(), r.Method, pattern)
+		recordRoute(r.Context(), pattern)
+		start := time.Now()
+		rec := &responseRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		app.metrics.apiRequests.WithLabelValues(pattern, r.Method).Inc()
+		app.metrics.requests.WithLabelValues(pattern, r.Method, strconv.Itoa(rec.statusCode())).Inc()
+		app.metrics.requestDuration.WithLabelValues(pattern, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
@@ -2,21 +2,28 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
 	"github.com/justinas/nosurf"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 /*
 This middleware adds security-related HTTP headers to every response.
 These headers help protect the application from common web vulnerabilities like cross-site scripting (XSS), clickjacking, and MIME-type sniffing.
 */
-func secureHeaders(next http.Handler) http.Handler {
+func (app *application) secureHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		w.Header().Set("Content-Security-Policy",
-			"default-src 'self'; style-src 'self' fonts.googleapis.com; font-src fonts.gstatic.com")
+		w.Header().Set("Content-Security-Policy", app.config.CSP)
 		w.Header().Set("Referrer-Policy", "origin-when-cross-origin")
 		w.Header().Set("X-Content-Type-Options", "nosniff")
 		w.Header().Set("X-Frame-Options", "deny")
@@ -28,26 +35,174 @@ func secureHeaders(next http.Handler) http.Handler {
 /*
 r.RemoteAddr is a field of the http.Request struct (r *http.Request) that contains the network address of the client making the request.
 r.Proto is a field of the http.Request struct that contains the HTTP protocol version used by the client.
-r.URL.RequestURI() is a method of the URL field in the http.Request struct that returns the request URI (Uniform Resource Identifier).
 
-The request URI includes the path and query string of the URL.
+The URI isn't logged as it came in: a snippet's slug is all it takes to read an unlisted snippet,
+so it must not end up in the logs. The line has the route pattern the request matched instead,
+and in the uri field the pattern takes the place of the path. Requests no route matched, like
+404s and those on the admin and redirect listeners, get their path with the slugs cut out.
 
 Example
-For a request to https://example.com/path?query=123, r.URL.RequestURI() will return:
+For a request to https://example.com/snippet/view/Ab3dE6gH?from=1, the line has
+route=/snippet/view/:slug and uri=/snippet/view/:slug?from=1
+
+The line is written once the handler returns, so it can include the status code, the number of
+bytes written and how long the request took.
 */
 func (app *application) logRequest(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		app.infoLog.Printf("%s - %s %s %s", r.RemoteAddr, r.Proto, r.Method, r.URL.RequestURI())
-		next.ServeHTTP(w, r)
+		start := time.Now()
+		rec := &responseRecorder{ResponseWriter: w}
+		route := &requestRoute{}
+		r = r.WithContext(context.WithValue(r.Context(), routeContextKey, route))
+
+		next.ServeHTTP(rec, r)
+
+		app.requestLogger(r).Info("request",
+			"remote_addr", r.RemoteAddr,
+			"proto", r.Proto,
+			"method", r.Method,
+			"route", route.pattern,
+			"uri", loggedURI(r),
+			"status", rec.statusCode(),
+			"bytes", rec.bytes,
+			"duration", time.Since(start),
+		)
 	})
 }
 
+// requestRoute is where instrument records the route pattern a request
+// matched, since logRequest runs before the router has picked one.
+type requestRoute struct {
+	pattern string
+}
+
+// recordRoute notes the matched route pattern for logRequest.
+func recordRoute(ctx context.Context, pattern string) {
+	if route, ok := ctx.Value(routeContextKey).(*requestRoute); ok {
+		route.pattern = pattern
+	}
+}
+
+// loggedURI is the request URI with the route pattern in place of the path,
+// or the path with its slugs redacted if no route matched.
+func loggedURI(r *http.Request) string {
+	path := redactSlugs(r.URL.Path)
+	if route, ok := r.Context().Value(routeContextKey).(*requestRoute); ok && route.pattern != "" {
+		path = route.pattern
+	}
+	if r.URL.RawQuery != "" {
+		path += "?" + r.URL.RawQuery
+	}
+	return path
+}
+
+// slugParents are the pairs of path segments a snippet's slug follows.
+var slugParents = map[string]bool{
+	"snippet/view":   true,
+	"snippet/edit":   true,
+	"snippet/delete": true,
+	"v1/snippets":    true,
+}
+
+// redactSlugs replaces the slug in a snippet's path with ":slug", so
+// /snippet/view/Ab3dE6gH/diff becomes /snippet/view/:slug/diff. It is used
+// where a path ends up in logs or traces.
+func redactSlugs(path string) string {
+	segments := strings.Split(path, "/")
+	for i := 2; i < len(segments); i++ {
+		if slugParents[segments[i-2]+"/"+segments[i-1]] && segments[i] != "" {
+			segments[i] = ":slug"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// responseRecorder remembers the status code and body size written through
+// it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// statusCode is the status sent to the client; a handler that writes nothing
+// gets an implicit 200.
+func (rec *responseRecorder) statusCode() int {
+	if rec.status == 0 {
+		return http.StatusOK
+	}
+	return rec.status
+}
+
+/*
+requestID gives every request an ID, taken from the X-Request-ID header when a proxy in front
+of us already assigned one and generated otherwise. It is echoed back in the response header and
+stored in the request context, where requestLogger picks it up.
+*/
+func (app *application) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("request.id", id))
+
+		ctx := context.WithValue(r.Context(), requestIDContextKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validRequestID accepts incoming IDs that are short and made only of
+// characters that are safe to put in a log line or header.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
 func (app *application) recoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
 				w.Header().Set("Connection", "close")
-				app.serverError(w, fmt.Errorf("%s", err))
+				app.serverError(w, r, fmt.Errorf("%s", err))
 			}
 		}()
 
@@ -65,6 +220,10 @@ func (app *application) requireAuthentication(next http.Handler) http.Handler {
 	})
 }
 func noSurf(next http.Handler) http.Handler {
+	return newCSRFHandler(next)
+}
+
+func newCSRFHandler(next http.Handler) *nosurf.CSRFHandler {
 	csrfHandler := nosurf.New(next)
 	csrfHandler.SetBaseCookie(http.Cookie{
 		HttpOnly: true,
@@ -73,26 +232,97 @@ func noSurf(next http.Handler) http.Handler {
 	})
 	return csrfHandler
 }
-func (app *application) authenticate(next http.Handler) http.Handler {
+
+/*
+tokenNoSurf is noSurf for the routes that also take API tokens. Requests carrying a token don't
+rely on cookies, so they can't be forged by another site and have no CSRF token to send. These
+routes use authenticateToken, which never falls back to the session when there is a token and
+rejects the request if the token is bad, so this can't be used to slip a cookie-authenticated
+request past the check.
+*/
+func tokenNoSurf(next http.Handler) http.Handler {
+	csrfHandler := newCSRFHandler(next)
+	csrfHandler.ExemptFunc(hasBearerToken)
+	return csrfHandler
+}
+
+// apiNoSurf applies the same CSRF protection as tokenNoSurf to the JSON API, so
+// a browser session can't be used to write through the API from another site.
+// Browser clients send the token in the X-CSRF-Token header.
+func (app *application) apiNoSurf(next http.Handler) http.Handler {
+	csrfHandler := newCSRFHandler(next)
+	csrfHandler.ExemptFunc(hasBearerToken)
+	csrfHandler.SetFailureHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.apiClientError(w, http.StatusForbidden, "missing or invalid CSRF token")
+	}))
+	return csrfHandler
+}
+
+/*
+sessionOnly turns away requests that bring an API token to routes only a browser should use, most
+importantly the token pages: a token that could list, create or revoke tokens would let anyone
+holding a short-lived one mint a never-expiring one. It comes before noSurf, so a misconfigured
+client is told why it isn't getting in rather than failing the CSRF check.
+*/
+func sessionOnly(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hasBearerToken(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_request"`)
+			http.Error(w, "API tokens are not accepted here, log in instead", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
 
+// authenticate reads the logged in user from the session.
+func (app *application) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
 		if id == 0 {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		exists, err := app.users.Exists(id)
+		exists, err := app.users.Exists(r.Context(), id)
 		if err != nil {
-			app.serverError(w, err)
+			app.serverError(w, r, err)
 			return
 		}
 
 		if exists {
 			ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+			ctx = context.WithValue(ctx, authenticatedUserIDContextKey, id)
 			r = r.WithContext(ctx)
 		}
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+// authenticateToken is authenticate for the JSON API and the routes that write
+// snippets, which CI jobs use. An API token takes precedence over the session
+// cookie.
+func (app *application) authenticateToken(next http.Handler) http.Handler {
+	session := app.authenticate(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			session.ServeHTTP(w, r)
+			return
+		}
+		id, err := app.tokens.Authenticate(r.Context(), token)
+		if err != nil {
+			if errors.Is(err, models.ErrInvalidCredentials) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				app.apiClientError(w, http.StatusUnauthorized, "invalid or expired API token")
+			} else {
+				app.serverError(w, r, err)
+			}
+			return
+		}
+		ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+		ctx = context.WithValue(ctx, authenticatedUserIDContextKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
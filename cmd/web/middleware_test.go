@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// A slug is enough to read an unlisted snippet, so neither the request log
+// nor the trace may have one.
+func TestSlugsAreNotLoggedOrTraced(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	const slug = "Ab3dE6gH"
+	tests := []struct {
+		name      string
+		target    string
+		wantRoute string
+		wantURI   string
+	}{
+		{"route", "/snippet/view/" + slug + "/diff?from=1&to=2", "/snippet/view/:slug/diff", "/snippet/view/:slug/diff?from=1&to=2"},
+		{"api", "/api/v1/snippets/" + slug, "/api/v1/snippets/:slug", "/api/v1/snippets/:slug"},
+		{"no route", "/snippet/view/" + slug + "/nope", "", "/snippet/view/:slug/nope"},
+		// httprouter strips the slash from the request before redirecting.
+		{"trailing slash", "/snippet/edit/" + slug + "/", "", "/snippet/edit/:slug"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			app := newTestApplication(t)
+			app.logger = slog.New(slog.NewJSONHandler(&logs, nil))
+			exporter.Reset()
+
+			app.routes().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			line := logs.String()
+			if strings.Contains(line, slug) {
+				t.Errorf("log has the slug: %s", line)
+			}
+			var entry struct {
+				Route string `json:"route"`
+				URI   string `json:"uri"`
+			}
+			if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+				t.Fatal(err)
+			}
+			if entry.Route != tt.wantRoute || entry.URI != tt.wantURI {
+				t.Errorf("got route %q and uri %q, want %q and %q", entry.Route, entry.URI, tt.wantRoute, tt.wantURI)
+			}
+
+			spans := exporter.GetSpans()
+			if len(spans) == 0 {
+				t.Fatal("no spans")
+			}
+			for _, span := range spans {
+				if strings.Contains(span.Name, slug) {
+					t.Errorf("span is named %q", span.Name)
+				}
+				for _, attr := range span.Attributes {
+					if strings.Contains(attr.Value.Emit(), slug) {
+						t.Errorf("span %q has %s=%s", span.Name, attr.Key, attr.Value.Emit())
+					}
+				}
+			}
+		})
+	}
+}
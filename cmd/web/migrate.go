@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/migrate"
+	"github.com/Vanshikav123/ByteFlow.git/migrations"
+)
+
+const migrateUsage = `usage: web [flags] migrate up|down [n]|status
+
+  up       apply all pending migrations
+  down     revert the last applied migration, or the last n
+  status   list migrations and when they were applied`
+
+// runMigrate implements the migrate subcommand. args are the command line
+// arguments after "migrate".
+func runMigrate(stores *stores, args []string, out io.Writer) error {
+	if stores.db == nil {
+		return fmt.Errorf("the %s backend has no schema to migrate", stores.driver)
+	}
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	fsys, err := fs.Sub(migrations.Files, stores.driver)
+	if err != nil {
+		return err
+	}
+	m := &migrate.Migrator{DB: stores.db, Driver: stores.driver, FS: fsys}
+
+	switch args[0] {
+	case "up":
+		ran, err := m.Up()
+		for _, mig := range ran {
+			fmt.Fprintf(out, "applied  %04d_%s\n", mig.Version, mig.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(ran) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return errors.New(migrateUsage)
+			}
+		}
+		ran, err := m.Down(steps)
+		for _, mig := range ran {
+			fmt.Fprintf(out, "reverted %04d_%s\n", mig.Version, mig.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(ran) == 0 {
+			fmt.Fprintln(out, "no applied migrations")
+		}
+	case "status":
+		status, err := m.Status()
+		if err != nil {
+			return err
+		}
+		for _, mig := range status {
+			state := "pending"
+			if !mig.Applied.IsZero() {
+				state = "applied " + mig.Applied.UTC().Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(out, "%04d_%-30s %s\n", mig.Version, mig.Name, state)
+		}
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+failureLimiter stops password guessing. It counts the failed attempts against each key, a snippet
+id for snippet passwords, and once there have been max of them in a window it refuses any more
+attempts until the window is over. An attempt is counted when it is reserved, before the password
+is checked, and given back if it turns out to be right. Refusing is done whether or not the next guess would be right,
+so an attacker learns nothing from it.
+
+The counts are kept in memory, so each instance of the server limits on its own and a restart
+forgets them, which still bounds guessing to a handful of tries per window per instance.
+*/
+type failureLimiter struct {
+	max    int
+	window time.Duration
+
+	mu       sync.Mutex
+	failures map[int]*failures
+}
+
+// failures are the failed attempts against a key since start.
+type failures struct {
+	start time.Time
+	count int
+}
+
+const (
+	// unlockMaxFailures wrong snippet passwords in unlockWindow lock a
+	// snippet until the window is over.
+	unlockMaxFailures = 5
+	unlockWindow      = 15 * time.Minute
+)
+
+func newFailureLimiter(max int, window time.Duration) *failureLimiter {
+	return &failureLimiter{max: max, window: window, failures: make(map[int]*failures)}
+}
+
+// Reserve reports whether an attempt against key may be made now and, if so,
+// counts it as a failure straight away. Counting it before the password is
+// checked means concurrent guesses can't all get through while the first ones
+// are still being checked. A right password gives the attempt back with
+// Refund. If the attempt may not be made, Reserve also returns how long until
+// it may.
+func (l *failureLimiter) Reserve(key int) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	f, ok := l.failures[key]
+	if !ok || now.Sub(f.start) >= l.window {
+		// Windows that are over are only removed when their key is tried
+		// again, so sweep them all out now and then.
+		if len(l.failures) >= 1024 {
+			for k, f := range l.failures {
+				if now.Sub(f.start) >= l.window {
+					delete(l.failures, k)
+				}
+			}
+		}
+		l.failures[key] = &failures{start: now, count: 1}
+		return true, 0
+	}
+	if f.count >= l.max {
+		return false, f.start.Add(l.window).Sub(now)
+	}
+	f.count++
+	return true, 0
+}
+
+// Refund gives back an attempt reserved against key that didn't fail.
+func (l *failureLimiter) Refund(key int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, ok := l.failures[key]
+	if !ok {
+		return
+	}
+	f.count--
+	if f.count <= 0 {
+		delete(l.failures, key)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+)
+
+// Wrong passwords sent all at once used to all get checked, since each was
+// only counted once bcrypt had turned it down. Reserving the attempt first
+// lets no more than unlockMaxFailures of them through.
+func TestConcurrentGuessesAreLimited(t *testing.T) {
+	app := newTestApplication(t)
+	ctx := context.Background()
+
+	err := app.users.Insert(ctx, "Alice", "alice@example.com", "pa$$word")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, err := app.users.Authenticate(ctx, "alice@example.com", "pa$$word")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, slug, err := app.snippets.Insert(ctx, userID, models.SnippetFields{
+		Title:      "Secret",
+		Content:    "hunter2",
+		Visibility: models.VisibilityUnlisted,
+		Password:   "correct horse",
+		Expires:    1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := app.routes()
+
+	guess := func(password string) int {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/snippets/"+slug, nil)
+		r.Header.Set("X-Snippet-Password", password)
+		w := httptest.NewRecorder()
+		routes.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	const guesses = 50
+	codes := make(chan int, guesses)
+	var wg sync.WaitGroup
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- guess("wrong")
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	counts := make(map[int]int)
+	for code := range codes {
+		counts[code]++
+	}
+	if counts[http.StatusUnauthorized] != unlockMaxFailures || counts[http.StatusTooManyRequests] != guesses-unlockMaxFailures {
+		t.Errorf("got status counts %v, want %d checked and the rest refused", counts, unlockMaxFailures)
+	}
+
+	// Even the right password is refused until the window is over.
+	if code := guess("correct horse"); code != http.StatusTooManyRequests {
+		t.Errorf("right password after the limit: got status %d, want %d", code, http.StatusTooManyRequests)
+	}
+}
+
+// A right password gives back the attempt it reserved, so it doesn't count
+// towards the limit.
+func TestFailureLimiterRefund(t *testing.T) {
+	l := newFailureLimiter(2, unlockWindow)
+
+	for i := 0; i < 10; i++ {
+		if ok, _ := l.Reserve(1); !ok {
+			t.Fatalf("attempt %d refused though every one was refunded", i+1)
+		}
+		l.Refund(1)
+	}
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Reserve(1); !ok {
+			t.Fatalf("failure %d refused", i+1)
+		}
+	}
+	if ok, wait := l.Reserve(1); ok || wait <= 0 || wait > unlockWindow {
+		t.Errorf("attempt after the limit: got %v, %v", ok, wait)
+	}
+	if ok, _ := l.Reserve(2); !ok {
+		t.Error("another key was refused")
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+/*
+reaper is a background worker that hard-deletes expired snippets. The models already hide
+expired snippets, but people paste secrets expecting the expiry option to really delete them,
+so every interval the reaper removes expired rows in batches until none are left.
+*/
+type reaper struct {
+	snippets  models.SnippetModelInterface
+	interval  time.Duration
+	batchSize int
+	logger    *slog.Logger
+
+	purged prometheus.Counter
+	errors prometheus.Counter
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newReaper(snippets models.SnippetModelInterface, interval time.Duration, batchSize int, logger *slog.Logger) *reaper {
+	r := &reaper{
+		snippets:  snippets,
+		interval:  interval,
+		batchSize: batchSize,
+		logger:    logger.With("component", "reaper"),
+		purged: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "gosnippet_snippets_purged_total",
+			Help: "Total number of expired snippets deleted by the reaper.",
+		}),
+		errors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "gosnippet_reaper_errors_total",
+			Help: "Total number of reaper runs that failed.",
+		}),
+	}
+	prometheus.MustRegister(r.purged, r.errors)
+	return r
+}
+
+// Start runs the reaper in its own goroutine until Stop is called.
+func (r *reaper) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			r.purge(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop tells the reaper to finish the batch it is working on and waits for it
+// to exit.
+func (r *reaper) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	r.wg.Wait()
+}
+
+// purge deletes batches of expired snippets until a batch comes back short,
+// checking between batches whether the reaper has been stopped.
+func (r *reaper) purge(ctx context.Context) {
+	total := 0
+	for ctx.Err() == nil {
+		n, err := r.snippets.DeleteExpired(ctx, r.batchSize)
+		if err != nil && ctx.Err() != nil {
+			// Stopped mid-batch; the transaction was rolled back.
+			return
+		}
+		if err != nil {
+			r.errors.Inc()
+			r.logger.Error("deleting expired snippets", "error", err)
+			return
+		}
+		total += n
+		r.purged.Add(float64(n))
+		if n < r.batchSize {
+			break
+		}
+	}
+	if total > 0 {
+		r.logger.Info("deleted expired snippets", "count", total)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"github.com/Vanshikav123/ByteFlow.git/ui"
 	"github.com/julienschmidt/httprouter"
 	"github.com/justinas/alice"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 /*
@@ -41,28 +40,94 @@ func (app *application) routes() http.Handler {
 	router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		app.notFound(w)
 	})
+
+	// handle registers a route with its pattern as the metrics label.
+	handle := func(method, pattern string, handler http.Handler) {
+		router.Handler(method, pattern, app.instrument(pattern, handler))
+	}
+
 	fileServer := http.FileServer(http.FS(ui.Files))
-	router.Handler(http.MethodGet, "/static/*filepath", fileServer)
+	handle(http.MethodGet, "/static/*filepath", fileServer)
 	// Unprotected application routes using the "dynamic" middleware chain.
-	dynamic := alice.New(app.sessionManager.LoadAndSave, noSurf, app.authenticate)
-	router.Handler(http.MethodGet, "/", dynamic.ThenFunc(app.withMetrics(app.home)))
-	router.Handler(http.MethodGet, "/snippet/view/:id", dynamic.ThenFunc(app.withMetrics(app.snippetView)))
-	router.Handler(http.MethodGet, "/user/signup", dynamic.ThenFunc(app.withMetrics(app.userSignup)))
-	router.Handler(http.MethodPost, "/user/signup", dynamic.ThenFunc(app.withMetrics(app.userSignupPost)))
-	router.Handler(http.MethodGet, "/user/login", dynamic.ThenFunc(app.withMetrics(app.userLogin)))
-	router.Handler(http.MethodPost, "/user/login", dynamic.ThenFunc(app.withMetrics(app.userLoginPost)))
+	dynamic := alice.New(
+		traceMiddleware("sessionOnly", sessionOnly),
+		traceMiddleware("LoadAndSave", app.sessionManager.LoadAndSave),
+		traceMiddleware("noSurf", noSurf),
+		traceMiddleware("authenticate", app.authenticate),
+	)
+	handle(http.MethodGet, "/", dynamic.ThenFunc(app.home))
+	handle(http.MethodGet, "/feed.atom", dynamic.ThenFunc(app.feed))
+	handle(http.MethodGet, "/snippet/search", dynamic.ThenFunc(app.snippetSearch))
+	handle(http.MethodGet, "/snippet/view/:slug", dynamic.ThenFunc(app.snippetView))
+	handle(http.MethodPost, "/snippet/view/:slug/unlock", dynamic.ThenFunc(app.snippetUnlockPost))
+	handle(http.MethodPost, "/snippet/view/:slug/read", dynamic.ThenFunc(app.snippetRead))
+	handle(http.MethodGet, "/snippet/view/:slug/history", dynamic.ThenFunc(app.snippetHistory))
+	handle(http.MethodGet, "/snippet/view/:slug/diff", dynamic.ThenFunc(app.snippetDiff))
+	handle(http.MethodGet, "/user/signup", dynamic.ThenFunc(app.userSignup))
+	handle(http.MethodPost, "/user/signup", dynamic.ThenFunc(app.userSignupPost))
+	handle(http.MethodGet, "/user/login", dynamic.ThenFunc(app.userLogin))
+	handle(http.MethodPost, "/user/login", dynamic.ThenFunc(app.userLoginPost))
 
 	// Protected (authenticated-only) application routes, using a new "protected"
 	// middleware chain which includes the requireAuthentication middleware.
-	protected := dynamic.Append(app.requireAuthentication)
-	router.Handler(http.MethodGet, "/snippet/create", protected.ThenFunc(app.withMetrics(app.snippetCreate)))
-	router.Handler(http.MethodPost, "/snippet/create", protected.ThenFunc(app.withMetrics(app.snippetCreatePost)))
-	router.Handler(http.MethodPost, "/user/logout", protected.ThenFunc(app.withMetrics(app.userLogoutPost)))
-
-	// Metrics endpoint
-	router.Handler(http.MethodGet, "/metrics", promhttp.Handler())
-
-	standard := alice.New(app.recoverPanic, app.logRequest, secureHeaders)
+	protected := dynamic.Append(traceMiddleware("requireAuthentication", app.requireAuthentication))
+	handle(http.MethodGet, "/snippet/create", protected.ThenFunc(app.snippetCreate))
+	handle(http.MethodGet, "/snippet/edit/:slug", protected.ThenFunc(app.snippetEdit))
+	handle(http.MethodGet, "/user/snippets", protected.ThenFunc(app.userSnippets))
+	handle(http.MethodGet, "/user/tokens", protected.ThenFunc(app.userTokens))
+	handle(http.MethodPost, "/user/tokens", protected.ThenFunc(app.userTokensPost))
+	handle(http.MethodPost, "/user/tokens/:id/revoke", protected.ThenFunc(app.userTokenRevokePost))
+	handle(http.MethodPost, "/user/logout", protected.ThenFunc(app.userLogoutPost))
+
+	// The routes that write snippets also take API tokens, so CI jobs can
+	// publish with one. Every other HTML route, the token pages above most of
+	// all, only takes the session.
+	tokenProtected := alice.New(
+		traceMiddleware("LoadAndSave", app.sessionManager.LoadAndSave),
+		traceMiddleware("tokenNoSurf", tokenNoSurf),
+		traceMiddleware("authenticateToken", app.authenticateToken),
+		traceMiddleware("requireAuthentication", app.requireAuthentication),
+	)
+	handle(http.MethodPost, "/snippet/create", tokenProtected.ThenFunc(app.snippetCreatePost))
+	handle(http.MethodPost, "/snippet/edit/:slug", tokenProtected.ThenFunc(app.snippetEditPost))
+	handle(http.MethodPost, "/snippet/view/:slug/restore", tokenProtected.ThenFunc(app.snippetRestorePost))
+	handle(http.MethodPost, "/snippet/delete/:slug", tokenProtected.ThenFunc(app.snippetDeletePost))
+
+	// JSON API. It shares the session and CSRF protection of the HTML routes
+	// but answers with JSON errors instead of redirects.
+	api := alice.New(
+		traceMiddleware("LoadAndSave", app.sessionManager.LoadAndSave),
+		traceMiddleware("apiNoSurf", app.apiNoSurf),
+		traceMiddleware("authenticateToken", app.authenticateToken),
+	)
+	handle(http.MethodGet, "/api/v1/snippets", api.ThenFunc(app.apiSnippetList))
+	handle(http.MethodGet, "/api/v1/snippets/:slug", api.ThenFunc(app.apiSnippetGet))
+	// Not under /api/v1/snippets, where it would clash with the :slug route.
+	handle(http.MethodGet, "/api/v1/search", api.ThenFunc(app.apiSearch))
+
+	apiProtected := api.Append(traceMiddleware("requireAPIAuthentication", app.requireAPIAuthentication))
+	handle(http.MethodPost, "/api/v1/snippets", apiProtected.ThenFunc(app.apiSnippetCreate))
+	handle(http.MethodPut, "/api/v1/snippets/:slug", apiProtected.ThenFunc(app.apiSnippetUpdate))
+	handle(http.MethodDelete, "/api/v1/snippets/:slug", apiProtected.ThenFunc(app.apiSnippetDelete))
+
+	// Health checks for the orchestrator. They are also on the admin listener,
+	// but probes usually only reach the public port, so they're served here
+	// too, without sessions or authentication. The public /readyz doesn't say
+	// why a check fails; that is only on the admin listener and in the logs.
+	// Metrics are served only by the admin listener, see adminRoutes.
+	handle(http.MethodGet, "/healthz", http.HandlerFunc(app.healthz))
+	handle(http.MethodGet, "/readyz", http.HandlerFunc(app.readyz))
+
+	// traceRequest starts the span everything else nests under. requestID comes
+	// next so every log line, including the one for a recovered panic, carries
+	// the ID; logRequest wraps recoverPanic to see the final status.
+	standard := alice.New(
+		app.traceRequest,
+		traceMiddleware("requestID", app.requestID),
+		traceMiddleware("logRequest", app.logRequest),
+		traceMiddleware("recoverPanic", app.recoverPanic),
+		traceMiddleware("secureHeaders", app.secureHeaders),
+	)
 
 	return standard.Then(router)
 }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-playground/form/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// newTestApplication is the application main builds, on the memory backend.
+func newTestApplication(t *testing.T) *application {
+	t.Helper()
+	stores, err := openStores("memory", "", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	templateCache, err := newTemplateCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionManager := scs.New()
+	sessionManager.Store = stores.sessions
+
+	return &application{
+		logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		config:         defaultConfig(),
+		snippets:       stores.snippets,
+		users:          stores.users,
+		revisions:      stores.revisions,
+		tokens:         stores.tokens,
+		search:         stores.search,
+		templateCache:  templateCache,
+		formDecoder:    form.NewDecoder(),
+		metrics:        newMetrics(prometheus.NewRegistry()),
+		sessionManager: sessionManager,
+		unlockLimiter:  newFailureLimiter(unlockMaxFailures, unlockWindow),
+	}
+}
+
+// A token must not be able to manage tokens, or a short-lived one could mint
+// one that never expires. It is still good for writing snippets.
+func TestBearerTokensOnlyWriteSnippets(t *testing.T) {
+	app := newTestApplication(t)
+	ctx := context.Background()
+
+	err := app.users.Insert(ctx, "Alice", "alice@example.com", "pa$$word")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, err := app.users.Authenticate(ctx, "alice@example.com", "pa$$word")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := app.tokens.Insert(ctx, userID, "ci", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := app.routes()
+
+	send := func(method, target, contentType, body string) int {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		if contentType != "" {
+			r.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		routes.ServeHTTP(w, r)
+		return w.Code
+	}
+	const formType = "application/x-www-form-urlencoded"
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		want        int
+	}{
+		{"list tokens", http.MethodGet, "/user/tokens", "", "", http.StatusUnauthorized},
+		{"create token", http.MethodPost, "/user/tokens", formType, url.Values{"name": {"forever"}, "expires": {"0"}}.Encode(), http.StatusUnauthorized},
+		{"revoke token", http.MethodPost, "/user/tokens/1/revoke", formType, "", http.StatusUnauthorized},
+		{"log out", http.MethodPost, "/user/logout", formType, "", http.StatusUnauthorized},
+		{"create snippet form", http.MethodPost, "/snippet/create", formType,
+			url.Values{"title": {"Build log"}, "content": {"ok"}, "visibility": {"private"}, "expires": {"1"}}.Encode(), http.StatusSeeOther},
+		{"create snippet API", http.MethodPost, "/api/v1/snippets", "application/json",
+			`{"title": "Build log", "content": "ok", "visibility": "private", "expires": 1}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := send(tt.method, tt.target, tt.contentType, tt.body); got != tt.want {
+				t.Errorf("got status %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	tokens, err := app.tokens.All(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 1 || tokens[0].Name != "ci" {
+		t.Errorf("tokens changed to %v", tokens)
+	}
+}
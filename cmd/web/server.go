@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+/*
+serve runs srv over HTTPS, and each of the plain HTTP listeners (admin and redirect) alongside it,
+until any of them fails or the process receives SIGINT or SIGTERM. It then stops all of them from
+accepting connections and gives in-flight
+requests up to shutdownTimeout to finish before returning, so a deploy doesn't cut off
+half-submitted snippets. A nil error means everything shut down cleanly.
+
+When stopped by a signal, /readyz starts failing at once, but the listeners stay open for
+shutdownDelay first so a load balancer polling it has time to stop sending new requests here.
+*/
+func (app *application) serve(srv *http.Server, plain []*http.Server, shutdownDelay, shutdownTimeout time.Duration) error {
+	serveError := make(chan error, 1+len(plain))
+	go func() {
+		// The certificate comes from srv.TLSConfig.GetCertificate.
+		serveError <- srv.ListenAndServeTLS("", "")
+	}()
+	for _, s := range plain {
+		go func() {
+			serveError <- s.ListenAndServe()
+		}()
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	// Whichever happens first ends serving. If one listener failed, the others
+	// are taken down with it rather than left running half a server.
+	var err error
+	select {
+	case sig := <-quit:
+		// A second signal skips the drain and exits immediately.
+		signal.Stop(quit)
+		app.shuttingDown.Store(true)
+		if shutdownDelay > 0 {
+			app.logger.Info("failing readiness before draining", "signal", sig.String(), "delay", shutdownDelay)
+			time.Sleep(shutdownDelay)
+		}
+		app.logger.Info("draining connections", "signal", sig.String(), "timeout", shutdownTimeout)
+	case err = <-serveError:
+		signal.Stop(quit)
+		app.shuttingDown.Store(true)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// Shutdown returns once the in-flight requests have finished or the
+	// timeout expires; ListenAndServe itself returns as soon as it's called.
+	shutdownErr := srv.Shutdown(ctx)
+	for _, s := range plain {
+		shutdownErr = errors.Join(shutdownErr, s.Shutdown(ctx))
+	}
+	if err = errors.Join(err, shutdownErr); err != nil {
+		return err
+	}
+	app.logger.Info("all connections drained")
+	return nil
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"html/template"
 	"io/fs"
 	"net/http"
 	"path/filepath"
-	"text/template"
+	"strings"
 	"time"
 
+	"github.com/Vanshikav123/ByteFlow.git/internal/diff"
 	"github.com/Vanshikav123/ByteFlow.git/internal/models"
 	"github.com/Vanshikav123/ByteFlow.git/ui"
 	"github.com/justinas/nosurf"
@@ -17,15 +19,44 @@ templateData struct is used to hold the data that will be passed to the HTML tem
 It represents the dynamic content and other variables that will be rendered in the templates
 */
 type templateData struct {
-	CurrentYear     int
-	Snippet         *models.Snippet
-	Snippets        []*models.Snippet
-	Form            any
-	Flash           string
-	IsAuthenticated bool
-	CSRFToken       string
+	CurrentYear         int
+	Snippet             *models.Snippet
+	Snippets            []*models.Snippet
+	Revisions           []*models.Revision
+	Diff                *revisionDiff
+	Search              *searchPage
+	Listing             *listingPage
+	Tokens              []*models.Token
+	NewToken            string
+	Form                any
+	Flash               string
+	IsAuthenticated     bool
+	AuthenticatedUserID int
+	CSRFToken           string
 }
 
+// revisionDiff is what diff.html needs to show the changes between two
+// versions of a snippet. TooDifferent is set instead of Hunks when the
+// contents are too different to diff.
+type revisionDiff struct {
+	From         *models.Revision
+	To           *models.Revision
+	Hunks        []diff.Hunk
+	TooDifferent bool
+}
+
+// searchPage is what search.html needs to show a page of search results.
+// Query is empty until something has been searched for.
+type searchPage struct {
+	Query   string
+	Results []*models.SearchResult
+	Page    int
+	HasMore bool
+}
+
+func (p *searchPage) PrevPage() int { return p.Page - 1 }
+func (p *searchPage) NextPage() int { return p.Page + 1 }
+
 /*
 Scenario
 Imagine you have a template html/pages/home.html with this content:
@@ -63,6 +94,11 @@ var functions = template.FuncMap{
 	"formatDate": func(t time.Time) string {
 		return t.Format("02 Jan 2006 at 15:04")
 	},
+	// join shows a list of tags in a single form field.
+	"join": strings.Join,
+	// languages and sortOrders are the options of the select fields.
+	"languages":  func() []string { return models.Languages },
+	"sortOrders": func() []models.SortOrder { return models.SortOrders },
 }
 
 func newTemplateCache() (map[string]*template.Template, error) {
@@ -119,8 +155,9 @@ func (app *application) newTemplateData(r *http.Request) *templateData {
 	return &templateData{
 		CurrentYear: time.Now().Year(),
 		/**/
-		Flash:           app.sessionManager.PopString(r.Context(), "flash"),
-		IsAuthenticated: app.isAuthenticated(r),
+		Flash:               app.sessionManager.PopString(r.Context(), "flash"),
+		IsAuthenticated:     app.isAuthenticated(r),
+		AuthenticatedUserID: app.authenticatedUserID(r),
 		/*The CSRF middleware (nosurf) validates the token before processing the request.
 		If the token is missing or incorrect, the request is rejected.
 		nosurf.Token(r) generates a unique CSRF token per session*/
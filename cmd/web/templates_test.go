@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/diff"
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+	"github.com/Vanshikav123/ByteFlow.git/internal/search"
+	"github.com/Vanshikav123/ByteFlow.git/internal/validator"
+)
+
+// payload is what a user could type into field hoping it ends up in a page as
+// markup. Each field gets its own so a failure says which one leaked.
+func payload(field string) string {
+	return fmt.Sprintf(`'"><script>alert("%s")</script>`, field)
+}
+
+// hostileForms are the forms of the pages that have one, filled in and
+// failing validation with payloads.
+func hostileForms() map[string]any {
+	var v validator.Validator
+	v.AddFieldError("title", payload("title error"))
+	v.AddFieldError("content", payload("content error"))
+	v.AddFieldError("language", payload("language error"))
+	v.AddFieldError("tags", payload("tags error"))
+	v.AddFieldError("visibility", payload("visibility error"))
+	v.AddFieldError("max_views", payload("max_views error"))
+	v.AddFieldError("password", payload("password error"))
+	v.AddFieldError("expires", payload("expires error"))
+	v.AddFieldError("name", payload("name error"))
+	v.AddFieldError("email", payload("email error"))
+	v.AddNonFieldError(payload("non-field error"))
+
+	snippet := snippetCreateForm{
+		Title:      payload("form title"),
+		Content:    payload("form content"),
+		Language:   payload("form language"),
+		Tags:       []string{payload("form tag")},
+		Visibility: models.Visibility(payload("form visibility")),
+		Password:   payload("form password"),
+		Validator:  v,
+	}
+	return map[string]any{
+		"create.html": snippet,
+		"edit.html":   snippet,
+		"signup.html": userSignupForm{Name: payload("form name"), Email: payload("form email"), Password: payload("form password"), Validator: v},
+		"login.html":  userLoginForm{Email: payload("form email"), Password: payload("form password"), Validator: v},
+		"tokens.html": tokenCreateForm{Name: payload("form token name"), Validator: v},
+		"unlock.html": snippetUnlockForm{Password: payload("form password"), Validator: v},
+	}
+}
+
+// hostileData fills every field of templateData that comes from users with
+// payloads.
+func hostileData() *templateData {
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	snippet := &models.Snippet{
+		ID:             1,
+		Slug:           "abcdefgh",
+		UserID:         2,
+		Author:         payload("author"),
+		Title:          payload("title"),
+		Content:        payload("content"),
+		Language:       payload("language"),
+		Visibility:     models.VisibilityUnlisted,
+		MaxViews:       3,
+		Views:          1,
+		HashedPassword: []byte("hash"),
+		Tags:           []string{payload("tag")},
+		Created:        created,
+		Expires:        created.AddDate(1, 0, 0),
+	}
+	from := &models.Revision{ID: 1, SnippetID: 1, Version: 1, UserID: 2, Author: payload("revision author"),
+		Title: payload("old revision title"), Content: payload("old revision content") + "\nkept\n", Created: created}
+	to := &models.Revision{ID: 2, SnippetID: 1, Version: 2, UserID: 2, Author: payload("revision author"),
+		Title: payload("new revision title"), Content: "kept\n" + payload("new revision content") + "\n", Created: created}
+	hunks, _ := diff.Unified(from.Content, to.Content, 3)
+
+	return &templateData{
+		CurrentYear: 2025,
+		Snippet:     snippet,
+		Snippets:    []*models.Snippet{snippet},
+		Revisions:   []*models.Revision{to, from},
+		Diff:        &revisionDiff{From: from, To: to, Hunks: hunks},
+		Search: &searchPage{
+			Query: payload("query"),
+			Results: []*models.SearchResult{{
+				Snippet: snippet,
+				Title:   search.Fragment{{Text: payload("result title")}, {Text: payload("matched title"), Match: true}},
+				Excerpts: []search.Fragment{
+					{{Text: payload("excerpt")}, {Text: payload("matched excerpt"), Match: true}},
+				},
+			}},
+			Page:    2,
+			HasMore: true,
+		},
+		Listing: &listingPage{
+			Path: "/",
+			Form: &listingForm{
+				Author:   2,
+				Tag:      payload("listing tag"),
+				Language: payload("listing language"),
+				From:     payload("listing from"),
+				To:       payload("listing to"),
+				Sort:     payload("listing sort"),
+				Cursor:   payload("listing cursor"),
+			},
+			Next: payload("next cursor"),
+		},
+		Tokens:              []*models.Token{{ID: 1, UserID: 2, Name: payload("token name"), Created: created}},
+		NewToken:            payload("new token"),
+		Flash:               payload("flash"),
+		IsAuthenticated:     true,
+		AuthenticatedUserID: 3,
+		CSRFToken:           payload("csrf token"),
+	}
+}
+
+// TestTemplatesEscapeUserInput renders every page with hostile data and
+// checks that none of it comes out as markup.
+func TestTemplatesEscapeUserInput(t *testing.T) {
+	cache, err := newTemplateCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cache) == 0 {
+		t.Fatal("no templates in the cache")
+	}
+
+	forms := hostileForms()
+	for page, ts := range cache {
+		t.Run(page, func(t *testing.T) {
+			data := hostileData()
+			data.Form = forms[page]
+
+			var buf bytes.Buffer
+			err := ts.ExecuteTemplate(&buf, "base", data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := buf.String()
+			// The flash is on every page, so it shows the payloads do get
+			// printed, only escaped.
+			if !strings.Contains(out, html.EscapeString(payload("flash"))) {
+				t.Errorf("%s doesn't show the flash", page)
+			}
+			if strings.Contains(out, "<script>alert(") {
+				i := strings.Index(out, "<script>alert(")
+				t.Errorf("unescaped payload in %s: %q", page, out[max(0, i-80):min(len(out), i+80)])
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/justinas/alice"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+/*
+certReloader serves the certificate in tls.cert_file and tls.key_file through
+tls.Config.GetCertificate, so it can be swapped without restarting the server. The files are
+loaded again when the process receives SIGHUP, and when their size or modification time changes
+(checked every reload interval), which covers certbot hooks and Kubernetes secret updates alike.
+
+A certificate that fails to load is logged and the previous one kept, so a half-written rotation
+never takes the site down.
+*/
+type certReloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+	logger   *slog.Logger
+
+	cert  atomic.Pointer[tls.Certificate]
+	stamp string
+
+	reloads *prometheus.CounterVec
+	expiry  prometheus.Gauge
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// newCertReloader loads the certificate for the first time. Unlike later
+// reloads, a failure here is returned, since there's nothing to fall back on.
+func newCertReloader(certFile, keyFile string, interval time.Duration, logger *slog.Logger) (*certReloader, error) {
+	r := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: interval,
+		logger:   logger.With("component", "certs"),
+		reloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gosnippet_tls_reloads_total",
+			Help: "Total number of TLS certificate reloads by result (success or failure).",
+		}, []string{"result"}),
+		expiry: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "gosnippet_tls_certificate_expiry_timestamp_seconds",
+			Help: "Unix time at which the TLS certificate being served expires.",
+		}),
+	}
+	r.stamp = r.fileStamp()
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	prometheus.MustRegister(r.reloads, r.expiry)
+	r.reloads.WithLabelValues("success")
+	r.reloads.WithLabelValues("failure")
+	return r, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// Start watches for SIGHUP and for changes to the files until Stop is called.
+func (r *certReloader) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer signal.Stop(hup)
+
+		// A nil channel never fires, so a zero interval leaves only SIGHUP.
+		var tick <-chan time.Time
+		if r.interval > 0 {
+			ticker := time.NewTicker(r.interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				r.logger.Info("reloading certificate", "reason", "SIGHUP")
+				r.stamp = r.fileStamp()
+				r.reload()
+			case <-tick:
+				// Record the new stamp even if loading fails, so a broken file
+				// is reported once rather than on every tick. Writing the
+				// other file of the pair changes the stamp again.
+				if stamp := r.fileStamp(); stamp != r.stamp {
+					r.stamp = stamp
+					r.logger.Info("reloading certificate", "reason", "files changed")
+					r.reload()
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops watching and waits for a reload in progress to finish.
+func (r *certReloader) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	r.wg.Wait()
+}
+
+func (r *certReloader) reload() {
+	if err := r.load(); err != nil {
+		r.reloads.WithLabelValues("failure").Inc()
+		r.logger.Error("reloading certificate, still serving the previous one", "error", err)
+		return
+	}
+	r.reloads.WithLabelValues("success").Inc()
+}
+
+func (r *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading TLS certificate: %w", err)
+	}
+	if cert.Leaf == nil {
+		cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return fmt.Errorf("loading TLS certificate: %w", err)
+		}
+	}
+	r.cert.Store(&cert)
+	r.expiry.Set(float64(cert.Leaf.NotAfter.Unix()))
+	r.logger.Info("loaded certificate", "subject", cert.Leaf.Subject.String(), "not_after", cert.Leaf.NotAfter)
+	return nil
+}
+
+// fileStamp summarises the size and modification time of both files. Missing
+// files stamp as empty, so their reappearance counts as a change.
+func (r *certReloader) fileStamp() string {
+	var stamp string
+	for _, path := range []string{r.certFile, r.keyFile} {
+		if fi, err := os.Stat(path); err == nil {
+			stamp += fmt.Sprintf("%d/%d;", fi.Size(), fi.ModTime().UnixNano())
+		} else {
+			stamp += ";"
+		}
+	}
+	return stamp
+}
+
+/*
+newACMEManager returns an autocert manager that obtains and renews certificates for
+tls.acme.domains from the ACME server at tls.acme.directory_url (Let's Encrypt by default),
+keeping them and the account key in tls.acme.cache_dir. Pointing directory_url at a staging
+server or a local Pebble, with ca_file set to that server's root, is the way to try it out.
+*/
+func newACMEManager(cfg *config) (*autocert.Manager, error) {
+	client := &acme.Client{DirectoryURL: cfg.TLS.ACME.DirectoryURL}
+
+	if cfg.TLS.ACME.CAFile != "" {
+		pem, err := os.ReadFile(cfg.TLS.ACME.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("acme: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("acme: no certificates found in %s", cfg.TLS.ACME.CAFile)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+		client.HTTPClient = &http.Client{Transport: transport}
+	}
+
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cfg.TLS.ACME.CacheDir),
+		HostPolicy: autocert.HostWhitelist(cfg.TLS.ACME.Domains...),
+		Email:      cfg.TLS.ACME.Email,
+		Client:     client,
+	}, nil
+}
+
+/*
+redirectRoutes is the handler for the plain HTTP redirect listener. It sends every request for one
+of the configured hosts (see config.redirectHosts) to the same host and path on the HTTPS address,
+and answers 400 for any other host, so it can't be made to redirect somewhere else by a forged
+Host header. With ACME on, the manager answers HTTP-01 challenges first; without a redirect
+listener the manager can only use TLS-ALPN-01, which needs the HTTPS listener on port 443.
+*/
+func (app *application) redirectRoutes(acmeManager *autocert.Manager) http.Handler {
+	var h http.Handler = http.HandlerFunc(app.redirectToHTTPS)
+	if acmeManager != nil {
+		h = acmeManager.HTTPHandler(h)
+	}
+	return alice.New(app.requestID, app.logRequest, app.recoverPanic).Then(h)
+}
+
+func (app *application) redirectToHTTPS(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		// No port in the Host header.
+		host = r.Host
+	}
+	// The target is built from the configured name, never from the header.
+	hosts := app.config.redirectHosts()
+	i := slices.IndexFunc(hosts, func(h string) bool {
+		return strings.EqualFold(h, strings.TrimSuffix(host, "."))
+	})
+	if i < 0 {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	host = hosts[i]
+	if _, port, err := net.SplitHostPort(app.config.Addr); err == nil && port != "" && port != "443" {
+		host = net.JoinHostPort(host, port)
+	}
+
+	// 308 keeps the method and body of a form post; plain links get the 301
+	// every client understands.
+	status := http.StatusPermanentRedirect
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		status = http.StatusMovedPermanently
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), status)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// The redirect listener only sends requests for configured hosts to HTTPS,
+// so a forged Host header can't turn it into an open redirect.
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		name         string
+		addr         string
+		method       string
+		host         string
+		wantStatus   int
+		wantLocation string
+	}{
+		{"configured host", ":443", http.MethodGet, "snippets.example.com", http.StatusMovedPermanently, "https://snippets.example.com/snippet/view/abc?x=1"},
+		{"ACME domain", ":443", http.MethodGet, "acme.example.com", http.StatusMovedPermanently, "https://acme.example.com/snippet/view/abc?x=1"},
+		{"port and case", ":443", http.MethodGet, "SNIPPETS.example.com:80", http.StatusMovedPermanently, "https://snippets.example.com/snippet/view/abc?x=1"},
+		{"trailing dot", ":443", http.MethodGet, "snippets.example.com.", http.StatusMovedPermanently, "https://snippets.example.com/snippet/view/abc?x=1"},
+		{"HTTPS on another port", ":4000", http.MethodGet, "snippets.example.com", http.StatusMovedPermanently, "https://snippets.example.com:4000/snippet/view/abc?x=1"},
+		{"post keeps the method", ":443", http.MethodPost, "snippets.example.com", http.StatusPermanentRedirect, "https://snippets.example.com/snippet/view/abc?x=1"},
+		{"other host", ":443", http.MethodGet, "evil.example", http.StatusBadRequest, ""},
+		{"subdomain", ":443", http.MethodGet, "evil.snippets.example.com", http.StatusBadRequest, ""},
+		{"no host", ":443", http.MethodGet, "", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.config.Addr = tt.addr
+			app.config.TLS.RedirectHosts = stringList{"snippets.example.com"}
+			app.config.TLS.ACME.Enabled = true
+			app.config.TLS.ACME.Domains = stringList{"acme.example.com"}
+
+			r := httptest.NewRequest(tt.method, "/snippet/view/abc?x=1", nil)
+			r.Host = tt.host
+			w := httptest.NewRecorder()
+			app.redirectToHTTPS(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("got Location %q, want %q", got, tt.wantLocation)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/justinas/alice"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/Vanshikav123/ByteFlow.git/cmd/web")
+
+/*
+setupTracing installs the global tracer provider for the tracing section of the config and
+returns a function that flushes and stops it. Exporters:
+
+	none    tracing is off; the global provider stays a no-op (the default)
+	stdout  pretty-printed spans on standard output, for local debugging
+	file    one JSON span per line appended to tracing.file; works offline
+	otlp    OTLP over HTTP to tracing.endpoint, or to OTEL_EXPORTER_OTLP_ENDPOINT
+	        (http://localhost:4318 if neither is set)
+*/
+func setupTracing(cfg *config) (shutdown func(context.Context) error, err error) {
+	noop := func(context.Context) error { return nil }
+
+	var exporter sdktrace.SpanExporter
+	var file *os.File
+	switch cfg.Tracing.Exporter {
+	case "none":
+		return noop, nil
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "file":
+		file, err = os.OpenFile(cfg.Tracing.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return noop, err
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	case "otlp":
+		var opts []otlptracehttp.Option
+		if cfg.Tracing.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Tracing.Endpoint))
+		}
+		if cfg.Tracing.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(context.Background(), opts...)
+	default:
+		return noop, fmt.Errorf("unsupported trace exporter %q", cfg.Tracing.Exporter)
+	}
+	if err != nil {
+		return noop, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName("byteflow"))),
+		// Respect the caller's sampling decision, sampling new traces at the
+		// configured ratio.
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	// Export failures happen in the background; without this they'd go to the
+	// standard logger at info level.
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		slog.Error("exporting traces", "exporter", cfg.Tracing.Exporter, "error", err)
+	}))
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		if file != nil {
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		return err
+	}, nil
+}
+
+/*
+traceRequest starts the server span for a request, continuing the trace of the caller when it sent
+a traceparent header. It is the outermost middleware, so every other span of the request is a
+child of this one. The span is named after the method only; instrument renames it once the route
+pattern is known. The path is recorded with slugs redacted, since a slug is enough to read an
+unlisted snippet.
+*/
+func (app *application) traceRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(redactSlugs(r.URL.Path)),
+				semconv.ClientAddress(r.RemoteAddr),
+			),
+		)
+		defer span.End()
+		// Middleware spans nest below this one, so keep a handle on it for
+		// setRoute.
+		ctx = context.WithValue(ctx, serverSpanContextKey, span)
+
+		rec := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		status := rec.statusCode()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// traceMiddleware wraps a middleware so the time spent in it, and in everything it
+// calls, shows up as a span named "middleware <name>".
+func traceMiddleware(name string, mw alice.Constructor) alice.Constructor {
+	return func(next http.Handler) http.Handler {
+		h := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, span := tracer.Start(r.Context(), "middleware "+name)
+			defer span.End()
+			h.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// setRoute names the request's server span after the matched route pattern.
+func setRoute(ctx context.Context, method, pattern string) {
+	span, ok := ctx.Value(serverSpanContextKey).(trace.Span)
+	if !ok {
+		return
+	}
+	span.SetName(method + " " + pattern)
+	span.SetAttributes(semconv.HTTPRoute(pattern))
+}
+
+// renderSpan starts the span covering template execution in render.
+func renderSpan(ctx context.Context, page string) trace.Span {
+	_, span := tracer.Start(ctx, "render "+page, trace.WithAttributes(attribute.String("template.page", page)))
+	return span
+}
@@ -0,0 +1,357 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Finding a shortest edit script takes time proportional to the number of
+// lines times the number of edits, so two long texts with nothing in common
+// are slow to compare. Texts past either limit aren't diffed at all.
+const (
+	MaxLines = 20_000 // lines of both texts together
+	MaxEdits = 2_000  // lines inserted and deleted
+)
+
+// ErrTooDifferent is returned for texts that are too long or too different to
+// diff within MaxLines and MaxEdits.
+var ErrTooDifferent = errors.New("diff: texts too different to compare")
+
+// Op says what happened to a line when going from the old text to the new one.
+type Op int
+
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+func (o Op) String() string {
+	switch o {
+	case Delete:
+		return "delete"
+	case Insert:
+		return "insert"
+	default:
+		return "equal"
+	}
+}
+
+// Prefix returns the character that starts a line with this op in a unified diff.
+func (o Op) Prefix() string {
+	switch o {
+	case Delete:
+		return "-"
+	case Insert:
+		return "+"
+	default:
+		return " "
+	}
+}
+
+// Line is a single line of a diff. OldLine and NewLine are 1-based line
+// numbers in each text and are 0 when the line doesn't appear on that side.
+type Line struct {
+	Op      Op
+	Text    string
+	OldLine int
+	NewLine int
+}
+
+// Hunk is a run of changes along with the unchanged lines surrounding it.
+type Hunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+	Lines              []Line
+
+	end int // index into the full edit script just past the last line added
+}
+
+// Header returns the unified diff range header, e.g. "@@ -1,4 +1,5 @@".
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+}
+
+func hunkRange(start, n int) string {
+	if n == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	if n == 0 {
+		// An empty range refers to the line before the change.
+		start--
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
+
+// Lines returns the line-by-line edit script turning a into b, or
+// ErrTooDifferent if it can't be found within MaxLines and MaxEdits.
+func Lines(a, b string) ([]Line, error) {
+	al, bl := splitLines(a), splitLines(b)
+	if len(al)+len(bl) > MaxLines {
+		return nil, ErrTooDifferent
+	}
+	return script(al, bl, MaxEdits)
+}
+
+// Unified groups the edit script turning a into b into hunks, keeping the given
+// number of unchanged context lines around each change. It returns nil if the
+// texts are identical, and ErrTooDifferent like Lines.
+func Unified(a, b string, context int) ([]Hunk, error) {
+	lines, err := Lines(a, b)
+	if err != nil {
+		return nil, err
+	}
+
+	var hunks []Hunk
+	end := -1
+	for i, l := range lines {
+		if l.Op == Equal {
+			continue
+		}
+		start := max(i-context, 0)
+		if len(hunks) == 0 || start > end {
+			if len(hunks) > 0 {
+				h := &hunks[len(hunks)-1]
+				h.Lines = append(h.Lines, lines[h.end:end]...)
+			}
+			hunks = append(hunks, Hunk{end: start})
+		}
+		h := &hunks[len(hunks)-1]
+		h.Lines = append(h.Lines, lines[h.end:i+1]...)
+		h.end = i + 1
+		end = min(i+1+context, len(lines))
+	}
+	if len(hunks) > 0 {
+		h := &hunks[len(hunks)-1]
+		h.Lines = append(h.Lines, lines[h.end:end]...)
+	}
+
+	for i := range hunks {
+		hunks[i].count()
+	}
+	return hunks, nil
+}
+
+// count fills in the line ranges of a hunk from its lines.
+func (h *Hunk) count() {
+	for _, l := range h.Lines {
+		if l.Op != Insert {
+			if h.OldStart == 0 {
+				h.OldStart = l.OldLine
+			}
+			h.OldLines++
+		}
+		if l.Op != Delete {
+			if h.NewStart == 0 {
+				h.NewStart = l.NewLine
+			}
+			h.NewLines++
+		}
+	}
+	// A hunk that only inserts or only deletes still needs a position on the
+	// other side; take it from the first line of the hunk.
+	first := h.Lines[0]
+	if h.OldStart == 0 {
+		h.OldStart = first.OldLine + 1
+	}
+	if h.NewStart == 0 {
+		h.NewStart = first.NewLine + 1
+	}
+}
+
+// Format renders hunks in the unified diff format used by diff -u and git.
+func Format(fromName, toName string, hunks []Hunk) string {
+	if len(hunks) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	for _, h := range hunks {
+		sb.WriteString(h.Header())
+		sb.WriteByte('\n')
+		for _, l := range h.Lines {
+			sb.WriteString(l.Op.Prefix())
+			sb.WriteString(l.Text)
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+// splitLines breaks text into lines, treating \r\n the same as \n and ignoring
+// a single trailing newline.
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// script computes a shortest edit script between a and b using the linear
+// space version of Myers' O(ND) algorithm: it finds the middle snake of an
+// optimal path and recurses on the two halves either side of it, so memory
+// stays proportional to the number of lines however different the texts are.
+// Lines are replaced by integers first, so comparing them is cheap. It gives up
+// with ErrTooDifferent once it's clear the script has more than maxEdits edits.
+func script(a, b []string, maxEdits int) ([]Line, error) {
+	ids := make(map[string]int, len(a)+len(b))
+	intern := func(lines []string) []int {
+		out := make([]int, len(lines))
+		for i, l := range lines {
+			id, ok := ids[l]
+			if !ok {
+				id = len(ids)
+				ids[l] = id
+			}
+			out[i] = id
+		}
+		return out
+	}
+
+	n := len(a) + len(b)
+	d := &differ{
+		a: a, b: b,
+		ai: intern(a), bi: intern(b),
+		vf:       make([]int, n+4),
+		vb:       make([]int, n+4),
+		lines:    make([]Line, 0, n),
+		maxEdits: maxEdits,
+	}
+	if !d.compare(0, len(a), 0, len(b)) {
+		return nil, ErrTooDifferent
+	}
+	return d.lines, nil
+}
+
+// differ holds the texts being compared and the edit script found so far.
+type differ struct {
+	a, b   []string
+	ai, bi []int
+	vf, vb []int // furthest reaching paths, reused by every middleSnake call
+	lines  []Line
+
+	edits, maxEdits int
+}
+
+// compare appends the edit script turning a[a0:a1] into b[b0:b1]. It reports
+// false if that takes more than maxEdits edits.
+func (d *differ) compare(a0, a1, b0, b1 int) bool {
+	// Common leading and trailing lines are taken off first, since revisions
+	// of the same snippet usually differ in only a few places.
+	for a0 < a1 && b0 < b1 && d.ai[a0] == d.bi[b0] {
+		d.equal(a0, b0)
+		a0++
+		b0++
+	}
+	suffix := 0
+	for a1 > a0 && b1 > b0 && d.ai[a1-1] == d.bi[b1-1] {
+		a1--
+		b1--
+		suffix++
+	}
+
+	switch {
+	case a0 == a1:
+		if d.edits += b1 - b0; d.edits > d.maxEdits {
+			return false
+		}
+		for y := b0; y < b1; y++ {
+			d.lines = append(d.lines, Line{Op: Insert, Text: d.b[y], NewLine: y + 1})
+		}
+	case b0 == b1:
+		if d.edits += a1 - a0; d.edits > d.maxEdits {
+			return false
+		}
+		for x := a0; x < a1; x++ {
+			d.lines = append(d.lines, Line{Op: Delete, Text: d.a[x], OldLine: x + 1})
+		}
+	default:
+		// Both sides are left with lines that differ at each end, so at
+		// least two edits are needed and both halves are strictly smaller.
+		x, y, u, v, ok := d.middleSnake(a0, a1, b0, b1)
+		if !ok || !d.compare(a0, x, b0, y) {
+			return false
+		}
+		for ; x < u; x, y = x+1, y+1 {
+			d.equal(x, y)
+		}
+		if !d.compare(u, a1, v, b1) {
+			return false
+		}
+	}
+
+	for i := 0; i < suffix; i++ {
+		d.equal(a1+i, b1+i)
+	}
+	return true
+}
+
+func (d *differ) equal(x, y int) {
+	d.lines = append(d.lines, Line{Op: Equal, Text: d.a[x], OldLine: x + 1, NewLine: y + 1})
+}
+
+// middleSnake finds the snake, from (x, y) to (u, v), in the middle of an
+// optimal path from (a0, b0) to (a1, b1). It runs the greedy search forwards
+// from the start and backwards from the end at the same time until they
+// overlap. The forward paths in vf are indexed by diagonal k = x - y, and the
+// backward ones in vb by the diagonal of the reversed texts, which is
+// delta - k.
+//
+// The snake is found after D steps in each direction, where D is about half
+// the number of edits between the two sides. Those are part of the whole
+// script, so once D passes maxEdits/2 the script is too long and ok is false.
+func (d *differ) middleSnake(a0, a1, b0, b1 int) (x, y, u, v int, ok bool) {
+	n, m := a1-a0, b1-b0
+	delta := n - m
+	maxD := (n + m + 1) / 2
+	limit := min(maxD, d.maxEdits/2+1)
+	off := maxD + 1
+	vf, vb := d.vf[:2*maxD+3], d.vb[:2*maxD+3]
+	vf[off+1], vb[off+1] = 0, 0
+
+	for D := 0; D <= limit; D++ {
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || (k != D && vf[off+k-1] < vf[off+k+1]) {
+				x = vf[off+k+1]
+			} else {
+				x = vf[off+k-1] + 1
+			}
+			y := x - k
+			sx, sy := x, y
+			for x < n && y < m && d.ai[a0+x] == d.bi[b0+y] {
+				x++
+				y++
+			}
+			vf[off+k] = x
+			if c := delta - k; delta%2 != 0 && c >= -(D-1) && c <= D-1 && x+vb[off+c] >= n {
+				return a0 + sx, b0 + sy, a0 + x, b0 + y, true
+			}
+		}
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || (k != D && vb[off+k-1] < vb[off+k+1]) {
+				x = vb[off+k+1]
+			} else {
+				x = vb[off+k-1] + 1
+			}
+			y := x - k
+			sx, sy := x, y
+			for x < n && y < m && d.ai[a1-1-x] == d.bi[b1-1-y] {
+				x++
+				y++
+			}
+			vb[off+k] = x
+			if c := delta - k; delta%2 == 0 && c >= -D && c <= D && x+vf[off+c] >= n {
+				return a1 - x, b1 - y, a1 - sx, b1 - sy, true
+			}
+		}
+	}
+	if limit < maxD {
+		return 0, 0, 0, 0, false
+	}
+	panic("diff: no middle snake")
+}
@@ -0,0 +1,202 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// lcs returns the length of the longest common subsequence of a and b, from
+// which the length of a shortest edit script follows.
+func lcs(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// check verifies that lines turns a into b and is as short as possible.
+func check(t *testing.T, a, b []string, lines []Line) {
+	t.Helper()
+	var old, new []string
+	edits := 0
+	for _, l := range lines {
+		if l.Op != Insert {
+			if l.OldLine != len(old)+1 {
+				t.Fatalf("line %q has old line %d, want %d", l.Text, l.OldLine, len(old)+1)
+			}
+			old = append(old, l.Text)
+		}
+		if l.Op != Delete {
+			if l.NewLine != len(new)+1 {
+				t.Fatalf("line %q has new line %d, want %d", l.Text, l.NewLine, len(new)+1)
+			}
+			new = append(new, l.Text)
+		}
+		if l.Op != Equal {
+			edits++
+		}
+	}
+	if strings.Join(old, "\n") != strings.Join(a, "\n") || strings.Join(new, "\n") != strings.Join(b, "\n") {
+		t.Fatalf("script of %q -> %q gives %q -> %q", a, b, old, new)
+	}
+	if want := len(a) + len(b) - 2*lcs(a, b); edits != want {
+		t.Fatalf("script of %q -> %q has %d edits, want %d", a, b, edits, want)
+	}
+}
+
+func TestScriptIsShortest(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	text := func() []string {
+		lines := make([]string, rng.Intn(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.Intn(4)))
+		}
+		return lines
+	}
+	for i := 0; i < 5000; i++ {
+		a, b := text(), text()
+		lines, err := script(a, b, len(a)+len(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, a, b, lines)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	hunks, err := Unified("a\nb\nc\nd\ne\nf\ng\nh\ni\n", "a\nb\nC\nd\ne\nf\ng\nh\ni\nj\n", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Format("old", "new", hunks)
+	want := `--- old
++++ new
+@@ -2,3 +2,3 @@
+ b
+-c
++C
+ d
+@@ -9 +9,2 @@
+ i
++j
+`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if hunks, err := Unified("same\n", "same", 3); hunks != nil || err != nil {
+		t.Errorf("identical texts gave %v, %v", hunks, err)
+	}
+}
+
+// rewrite returns n lines, and n lines with every one of them changed.
+func rewrite(n int) (a, b []string) {
+	for i := 0; i < n; i++ {
+		a = append(a, fmt.Sprintf("old line %d", i))
+		b = append(b, fmt.Sprintf("new line %d", i))
+	}
+	return a, b
+}
+
+// A completely rewritten snippet used to keep a copy of the paths for every
+// edit, which for texts this size took more than a gigabyte.
+func TestRewriteUsesLinearMemory(t *testing.T) {
+	a, b := rewrite(4000)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	lines, err := script(a, b, len(a)+len(b))
+	runtime.ReadMemStats(&after)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 8000 {
+		t.Fatalf("got %d lines", len(lines))
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 16<<20 {
+		t.Errorf("allocated %d bytes", allocated)
+	}
+}
+
+// Diffing two texts of the largest size a snippet can have with every line
+// changed used to take seconds; now it gives up.
+func TestRewriteIsTooDifferent(t *testing.T) {
+	for _, n := range []int{32_768, MaxLines / 2, MaxEdits} {
+		a, b := rewrite(n)
+		_, err := Unified(strings.Join(a, "\n"), strings.Join(b, "\n"), 3)
+		if !errors.Is(err, ErrTooDifferent) {
+			t.Errorf("rewriting %d lines: got %v, want ErrTooDifferent", n, err)
+		}
+	}
+}
+
+// MaxEdits is the length of the longest script Lines finds, whether the edits
+// are in one place or spread out.
+func TestMaxEdits(t *testing.T) {
+	tests := []struct {
+		name    string
+		changed []int // lines of a changed in b
+		wantErr bool
+	}{
+		{"spread at the limit", spread(MaxEdits / 2), false},
+		{"spread past the limit", spread(MaxEdits/2 + 1), true},
+		{"together at the limit", together(MaxEdits / 2), false},
+		{"together past the limit", together(MaxEdits/2 + 1), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := make([]string, 5000)
+			for i := range a {
+				a[i] = fmt.Sprintf("line %d", i)
+			}
+			b := slices.Clone(a)
+			for _, i := range tt.changed {
+				b[i] = "changed " + b[i]
+			}
+
+			lines, err := Lines(strings.Join(a, "\n"), strings.Join(b, "\n"))
+			if tt.wantErr {
+				if !errors.Is(err, ErrTooDifferent) {
+					t.Fatalf("got %v, want ErrTooDifferent", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			check(t, a, b, lines)
+		})
+	}
+}
+
+// spread returns n lines evenly spread over 5000.
+func spread(n int) []int {
+	var lines []int
+	for i := 0; i < n; i++ {
+		lines = append(lines, i*5000/n)
+	}
+	return lines
+}
+
+// together returns n lines in a row in the middle of 5000.
+func together(n int) []int {
+	var lines []int
+	for i := 0; i < n; i++ {
+		lines = append(lines, 2500-n/2+i)
+	}
+	return lines
+}
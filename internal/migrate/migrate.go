@@ -0,0 +1,275 @@
+// Package migrate applies the versioned SQL schema migrations embedded in the
+// migrations package and records which ones have run in a migrations table.
+package migrate
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration is one schema change. Applied is the zero time if the migration
+// hasn't been run against the database yet.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+	Applied time.Time
+}
+
+// Migrator runs migrations read from FS against DB. Driver is the -db-driver
+// name and decides the SQL used for the migrations table.
+type Migrator struct {
+	DB     *sql.DB
+	Driver string
+	FS     fs.FS
+}
+
+// Load reads every migration in FS, ordered by version. Each version must have
+// both an .up.sql and a .down.sql file.
+func (m *Migrator) Load() ([]*Migration, error) {
+	files, err := fs.Glob(m.FS, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, file := range files {
+		base := path.Base(file)
+		var direction string
+		switch {
+		case strings.HasSuffix(base, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(base, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migrate: %s is not named <version>_<name>.up.sql or .down.sql", base)
+		}
+
+		prefix, name, ok := strings.Cut(strings.TrimSuffix(base, "."+direction+".sql"), "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version < 1 {
+			return nil, fmt.Errorf("migrate: %s does not start with a version number", base)
+		}
+
+		body, err := fs.ReadFile(m.FS, file)
+		if err != nil {
+			return nil, err
+		}
+
+		mig, exists := byVersion[version]
+		if !exists {
+			mig = &Migration{Version: version, Name: name}
+			byVersion[version] = mig
+		} else if mig.Name != name {
+			return nil, fmt.Errorf("migrate: version %d is used by both %q and %q", version, mig.Name, name)
+		}
+		if direction == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migrate: version %d (%s) needs both an up and a down file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Status returns every known migration with Applied filled in for the ones
+// that have been run.
+func (m *Migrator) Status() ([]*Migration, error) {
+	err := m.ensureTable()
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := m.Load()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	for _, mig := range migrations {
+		mig.Applied = applied[mig.Version]
+	}
+	return migrations, nil
+}
+
+// Up applies every pending migration in order and returns the ones it ran.
+// It stops at the first failure; migrations before it stay applied.
+func (m *Migrator) Up() ([]*Migration, error) {
+	migrations, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []*Migration
+	for _, mig := range migrations {
+		if !mig.Applied.IsZero() {
+			continue
+		}
+		err = m.run(mig, mig.Up, `INSERT INTO migrations (version, name, applied) VALUES (?, ?, ?)`, mig.Version, mig.Name, time.Now().UTC())
+		if err != nil {
+			return ran, err
+		}
+		ran = append(ran, mig)
+	}
+	return ran, nil
+}
+
+// Down reverts the given number of most recently applied migrations and
+// returns the ones it reverted.
+func (m *Migrator) Down(steps int) ([]*Migration, error) {
+	migrations, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []*Migration
+	for i := len(migrations) - 1; i >= 0 && len(ran) < steps; i-- {
+		mig := migrations[i]
+		if mig.Applied.IsZero() {
+			continue
+		}
+		err = m.run(mig, mig.Down, `DELETE FROM migrations WHERE version = ?`, mig.Version)
+		if err != nil {
+			return ran, err
+		}
+		ran = append(ran, mig)
+	}
+	return ran, nil
+}
+
+// run executes the statements of one migration and the bookkeeping statement
+// in a single transaction. MySQL commits DDL implicitly so there a failure
+// part-way through may need cleaning up by hand; Postgres and SQLite roll the
+// whole migration back.
+func (m *Migrator) run(mig *Migration, script string, bookkeeping string, args ...any) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range splitStatements(script) {
+		if _, err = tx.Exec(stmt); err != nil {
+			return fmt.Errorf("migrate: %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+	}
+	if _, err = tx.Exec(m.rebind(bookkeeping), args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) ensureTable() error {
+	var stmt string
+	switch m.Driver {
+	case "postgres":
+		stmt = `CREATE TABLE IF NOT EXISTS migrations (
+    version INTEGER PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    applied TIMESTAMPTZ NOT NULL
+)`
+	case "mysql", "sqlite":
+		stmt = `CREATE TABLE IF NOT EXISTS migrations (
+    version INTEGER NOT NULL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    applied DATETIME NOT NULL
+)`
+	default:
+		return fmt.Errorf("migrate: unsupported driver %q", m.Driver)
+	}
+	_, err := m.DB.Exec(stmt)
+	return err
+}
+
+// applied returns the time each applied migration was run, by version.
+func (m *Migrator) applied() (map[int]time.Time, error) {
+	rows, err := m.DB.Query(`SELECT version, applied FROM migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err = rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// rebind rewrites ? placeholders as $1, $2... for Postgres.
+func (m *Migrator) rebind(stmt string) string {
+	if m.Driver != "postgres" {
+		return stmt
+	}
+	var sb strings.Builder
+	n := 0
+	for _, r := range stmt {
+		if r == '?' {
+			n++
+			sb.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// splitStatements breaks a migration file into statements, which must each end
+// with a semicolon at the end of a line. Not every driver accepts several
+// statements in one Exec call (MySQL needs multiStatements=true in the DSN).
+// A line ending in BEGIN, as in CREATE TRIGGER, starts a block that runs to a
+// line reading END; so the statements inside it stay together.
+func splitStatements(script string) []string {
+	var stmts []string
+	var current strings.Builder
+	inBlock := false
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if current.Len() == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "--")) {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteByte('\n')
+		upper := strings.ToUpper(trimmed)
+		switch {
+		case strings.HasSuffix(upper, "BEGIN"):
+			inBlock = true
+		case inBlock && upper != "END;":
+		case strings.HasSuffix(trimmed, ";"):
+			inBlock = false
+			stmts = append(stmts, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	return stmts
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// SortOrder is the order Find returns snippets in.
+type SortOrder string
+
+const (
+	SortNewest SortOrder = "newest" // most recently created first
+	SortOldest SortOrder = "oldest" // least recently created first
+	SortTitle  SortOrder = "title"  // by title, A to Z
+)
+
+// SortOrders lists the orders in the order they're offered to users; the
+// first one is the default.
+var SortOrders = []SortOrder{SortNewest, SortOldest, SortTitle}
+
+// ValidSortOrder reports whether s is "" (the default) or one of SortOrders.
+func ValidSortOrder(s SortOrder) bool {
+	return s == "" || slices.Contains(SortOrders, s)
+}
+
+/*
+ListQuery says which live snippets Find returns. The zero value of every filter field means "any",
+so ListQuery{Limit: 10} is the ten newest public snippets. Unlisted and private snippets are only
+ever listed for their author, the ViewerID.
+
+Pages are found with a cursor rather than an offset: After is the Next cursor of the previous page,
+and the next page starts right after the last snippet of that one. Unlike with an offset, snippets
+created or deleted in the meantime don't make a page repeat or skip any, and the database finds
+the start of a page through an index instead of counting its way there.
+*/
+type ListQuery struct {
+	ViewerID int       // user the listing is for, 0 if anonymous
+	AuthorID int       // only snippets by this user
+	Tag      string    // only snippets with this tag
+	Language string    // only snippets in this language
+	From     time.Time // only snippets created at or after this time
+	To       time.Time // only snippets created before this time
+	Sort     SortOrder
+	Limit    int    // page size
+	After    string // cursor of the page to return, "" for the first
+}
+
+// SnippetPage is a page of Find results. Next is the cursor of the page after
+// it, or "" if this is the last.
+type SnippetPage struct {
+	Snippets []*Snippet
+	Next     string
+}
+
+// Cursor is the position a page starts after: the sort key of the last
+// snippet on the page before it. Title is only set when sorting by title.
+type Cursor struct {
+	Sort  SortOrder `json:"s"`
+	ID    int       `json:"i"`
+	Title string    `json:"t,omitempty"`
+}
+
+// Order returns the sort order of the query, with "" meaning SortNewest.
+func (q ListQuery) Order() SortOrder {
+	if q.Sort == "" {
+		return SortNewest
+	}
+	return q.Sort
+}
+
+// Cursor decodes After. ok is false for the first page. A cursor that can't
+// be decoded, or was made for another sort order, is ErrInvalidCursor.
+func (q ListQuery) Cursor() (c Cursor, ok bool, err error) {
+	if q.After == "" {
+		return Cursor{}, false, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(q.After)
+	if err != nil {
+		return Cursor{}, false, ErrInvalidCursor
+	}
+	if err = json.Unmarshal(b, &c); err != nil || c.Sort != q.Order() || c.ID < 1 {
+		return Cursor{}, false, ErrInvalidCursor
+	}
+	return c, true, nil
+}
+
+// String encodes the cursor as URL-safe text. It's opaque to clients; it
+// isn't signed since it only says where to start reading.
+func (c Cursor) String() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// NewSnippetPage builds a page from the snippets a backend found for q. Every
+// backend asks for Limit+1 rows: if the extra one is there it's dropped and
+// there is a next page, starting after the last snippet kept.
+func NewSnippetPage(q ListQuery, snippets []*Snippet) *SnippetPage {
+	page := &SnippetPage{Snippets: snippets}
+	if q.Limit > 0 && len(snippets) > q.Limit {
+		page.Snippets = snippets[:q.Limit]
+		last := page.Snippets[len(page.Snippets)-1]
+		c := Cursor{Sort: q.Order(), ID: last.ID}
+		if c.Sort == SortTitle {
+			c.Title = last.Title
+		}
+		page.Next = c.String()
+	}
+	return page
+}
@@ -0,0 +1,65 @@
+// Package memory implements the models store interfaces with plain Go maps.
+// Nothing is persisted, which makes it handy for running the application and
+// its handlers without a database server.
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+	"github.com/Vanshikav123/ByteFlow.git/internal/search"
+)
+
+// DB holds every table. The models in this package share one DB, the same way
+// the MySQL models share one *sql.DB, and a single lock guards all of it so
+// that changes spanning tables (a snippet and its revisions) are atomic.
+type DB struct {
+	mu sync.RWMutex
+
+	users     map[int]*models.User
+	snippets  map[int]*models.Snippet
+	slugs     map[string]int             // snippet ids by slug
+	revisions map[int][]*models.Revision // keyed by snippet id, oldest first
+	tokens    map[int]*token
+
+	// index is the full-text index of snippets for SearchModel. The
+	// SnippetModel methods that change snippets keep it up to date.
+	index *search.Index
+
+	lastUserID     int
+	lastSnippetID  int
+	lastRevisionID int
+	lastTokenID    int
+}
+
+// token is a models.Token plus the hash that identifies it.
+type token struct {
+	models.Token
+	hash []byte
+}
+
+func NewDB() *DB {
+	return &DB{
+		users:     make(map[int]*models.User),
+		snippets:  make(map[int]*models.Snippet),
+		slugs:     make(map[string]int),
+		revisions: make(map[int][]*models.Revision),
+		tokens:    make(map[int]*token),
+		index:     search.NewIndex(),
+	}
+}
+
+// now returns the current time with the same precision and time zone as the
+// UTC_TIMESTAMP() values stored by MySQL.
+func (db *DB) now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// authorName returns the name of a user. Callers must hold at least a read lock.
+func (db *DB) authorName(userID int) string {
+	if u, ok := db.users[userID]; ok {
+		return u.Name
+	}
+	return ""
+}
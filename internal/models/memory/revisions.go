@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"context"
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+)
+
+type RevisionModel struct {
+	DB *DB
+}
+
+var _ models.RevisionModelInterface = (*RevisionModel)(nil)
+
+func (m *RevisionModel) All(ctx context.Context, snippetID int) ([]*models.Revision, error) {
+	m.DB.mu.RLock()
+	defer m.DB.mu.RUnlock()
+
+	stored := m.DB.revisions[snippetID]
+	revisions := make([]*models.Revision, 0, len(stored))
+	for i := len(stored) - 1; i >= 0; i-- {
+		revisions = append(revisions, m.DB.revisionCopy(stored[i]))
+	}
+	return revisions, nil
+}
+
+func (m *RevisionModel) Get(ctx context.Context, snippetID, version int) (*models.Revision, error) {
+	m.DB.mu.RLock()
+	defer m.DB.mu.RUnlock()
+
+	for _, r := range m.DB.revisions[snippetID] {
+		if r.Version == version {
+			return m.DB.revisionCopy(r), nil
+		}
+	}
+	return nil, models.ErrNoRecord
+}
+
+// addRevision appends the next version of a snippet. Callers must hold the
+// write lock.
+func (db *DB) addRevision(snippetID, userID int, title, content string) {
+	db.lastRevisionID++
+	db.revisions[snippetID] = append(db.revisions[snippetID], &models.Revision{
+		ID:        db.lastRevisionID,
+		SnippetID: snippetID,
+		Version:   len(db.revisions[snippetID]) + 1,
+		UserID:    userID,
+		Title:     title,
+		Content:   content,
+		Created:   db.now(),
+	})
+}
+
+// revisionCopy returns a copy of r with the author filled in. Callers must
+// hold at least a read lock.
+func (db *DB) revisionCopy(r *models.Revision) *models.Revision {
+	c := *r
+	c.Author = db.authorName(r.UserID)
+	return &c
+}
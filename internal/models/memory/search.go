@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+	"github.com/Vanshikav123/ByteFlow.git/internal/search"
+)
+
+// SearchModel searches snippets with the in-process index kept in DB.
+type SearchModel struct {
+	DB *DB
+}
+
+var _ models.SearchModelInterface = (*SearchModel)(nil)
+
+func (m *SearchModel) Search(ctx context.Context, query string, viewerID, limit, offset int) ([]*models.SearchResult, error) {
+	terms := search.Terms(query)
+	results := []*models.SearchResult{}
+	if len(terms) == 0 {
+		return results, nil
+	}
+
+	m.DB.mu.RLock()
+	defer m.DB.mu.RUnlock()
+
+	// Expired snippets stay in the index until the reaper deletes them, so
+	// they're skipped here rather than counted towards offset, as are those
+	// the viewer can't see.
+	for _, hit := range m.DB.index.Search(terms) {
+		s, ok := m.DB.live(hit.ID)
+		if !ok || !listed(s, viewerID) {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		if len(results) == limit {
+			break
+		}
+		results = append(results, models.NewSearchResult(m.DB.snippetCopy(s), hit.Score, terms))
+	}
+	return results, nil
+}
@@ -0,0 +1,258 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+)
+
+type SnippetModel struct {
+	DB   *DB
+	Cost int // bcrypt cost for snippet passwords; zero means models.DefaultBcryptCost
+}
+
+var _ models.SnippetModelInterface = (*SnippetModel)(nil)
+
+func (m *SnippetModel) Insert(ctx context.Context, userID int, f models.SnippetFields) (int, string, error) {
+	slug, err := models.NewSlug()
+	if err != nil {
+		return 0, "", err
+	}
+	hashedPassword, err := models.HashSnippetPassword(f.Password, m.Cost)
+	if err != nil {
+		return 0, "", err
+	}
+	m.DB.mu.Lock()
+	defer m.DB.mu.Unlock()
+
+	now := m.DB.now()
+	m.DB.lastSnippetID++
+	s := &models.Snippet{
+		ID:             m.DB.lastSnippetID,
+		Slug:           slug,
+		UserID:         userID,
+		Title:          f.Title,
+		Content:        f.Content,
+		Language:       f.Language,
+		Tags:           slices.Clone(f.Tags),
+		Visibility:     f.Visibility,
+		MaxViews:       f.MaxViews,
+		HashedPassword: hashedPassword,
+		Created:        now,
+		Expires:        now.AddDate(0, 0, f.Expires),
+	}
+	m.DB.snippets[s.ID] = s
+	m.DB.slugs[slug] = s.ID
+	m.DB.addRevision(s.ID, userID, f.Title, f.Content)
+	m.DB.index.Put(s.ID, f.Title, f.Content)
+	return s.ID, slug, nil
+}
+
+// live returns the snippet with the given id if it exists and has not expired.
+// Callers must hold at least a read lock.
+func (db *DB) live(id int) (*models.Snippet, bool) {
+	s, ok := db.snippets[id]
+	if !ok || !s.Expires.After(db.now()) {
+		return nil, false
+	}
+	return s, true
+}
+
+// listed reports whether s shows up in the listings and searches of viewerID,
+// as with the visibility conditions of the SQL backends.
+func listed(s *models.Snippet, viewerID int) bool {
+	return s.Visibility == models.VisibilityPublic || (viewerID != 0 && s.UserID == viewerID)
+}
+
+// snippetCopy returns a copy of s with the author filled in, so callers can't
+// modify the stored snippet. Callers must hold at least a read lock.
+func (db *DB) snippetCopy(s *models.Snippet) *models.Snippet {
+	c := *s
+	c.Author = db.authorName(s.UserID)
+	c.Tags = slices.Clone(s.Tags)
+	if c.Tags == nil {
+		c.Tags = []string{}
+	}
+	return &c
+}
+
+func (m *SnippetModel) Get(ctx context.Context, id int) (*models.Snippet, error) {
+	m.DB.mu.RLock()
+	defer m.DB.mu.RUnlock()
+
+	s, ok := m.DB.live(id)
+	if !ok {
+		return nil, models.ErrNoRecord
+	}
+	return m.DB.snippetCopy(s), nil
+}
+
+func (m *SnippetModel) GetBySlug(ctx context.Context, slug string) (*models.Snippet, error) {
+	m.DB.mu.RLock()
+	defer m.DB.mu.RUnlock()
+
+	s, ok := m.DB.live(m.DB.slugs[slug])
+	if !ok {
+		return nil, models.ErrNoRecord
+	}
+	return m.DB.snippetCopy(s), nil
+}
+
+// LegacySlug never finds anything, as nothing in memory outlives the process
+// and so every snippet has always had a slug.
+func (m *SnippetModel) LegacySlug(ctx context.Context, id int) (string, error) {
+	return "", models.ErrNoRecord
+}
+
+// Consume counts the view and deletes the snippet after its last one under
+// the write lock, which makes it as atomic as the transaction of the SQL
+// backends.
+func (m *SnippetModel) Consume(ctx context.Context, id int) (*models.Snippet, error) {
+	m.DB.mu.Lock()
+	defer m.DB.mu.Unlock()
+
+	s, ok := m.DB.live(id)
+	if !ok || !s.Limited() || s.ViewsLeft() == 0 {
+		return nil, models.ErrNoRecord
+	}
+	s.Views++
+	c := m.DB.snippetCopy(s)
+	if s.ViewsLeft() == 0 {
+		m.DB.deleteSnippet(s)
+	}
+	return c, nil
+}
+
+func (m *SnippetModel) Update(ctx context.Context, id, userID int, f models.SnippetFields) error {
+	m.DB.mu.Lock()
+	defer m.DB.mu.Unlock()
+
+	s, ok := m.DB.snippets[id]
+	if !ok {
+		return models.ErrNoRecord
+	}
+	s.Title = f.Title
+	s.Content = f.Content
+	s.Language = f.Language
+	s.Visibility = f.Visibility
+	s.Tags = slices.Clone(f.Tags)
+	s.Expires = m.DB.now().AddDate(0, 0, f.Expires)
+	m.DB.addRevision(id, userID, f.Title, f.Content)
+	m.DB.index.Put(id, f.Title, f.Content)
+	return nil
+}
+
+func (m *SnippetModel) Restore(ctx context.Context, id, userID, version int) error {
+	m.DB.mu.Lock()
+	defer m.DB.mu.Unlock()
+
+	s, ok := m.DB.snippets[id]
+	if !ok {
+		return models.ErrNoRecord
+	}
+	i := slices.IndexFunc(m.DB.revisions[id], func(r *models.Revision) bool {
+		return r.Version == version
+	})
+	if i < 0 {
+		return models.ErrNoRecord
+	}
+	r := m.DB.revisions[id][i]
+	s.Title = r.Title
+	s.Content = r.Content
+	m.DB.addRevision(id, userID, r.Title, r.Content)
+	m.DB.index.Put(id, r.Title, r.Content)
+	return nil
+}
+
+// Delete removes a snippet and, like the foreign key in MySQL, its revisions.
+func (m *SnippetModel) Delete(ctx context.Context, id int) error {
+	m.DB.mu.Lock()
+	defer m.DB.mu.Unlock()
+
+	s, ok := m.DB.snippets[id]
+	if !ok {
+		return models.ErrNoRecord
+	}
+	m.DB.deleteSnippet(s)
+	return nil
+}
+
+// deleteSnippet removes s from the store and the search index. Callers must
+// hold the write lock.
+func (db *DB) deleteSnippet(s *models.Snippet) {
+	delete(db.snippets, s.ID)
+	delete(db.slugs, s.Slug)
+	delete(db.revisions, s.ID)
+	db.index.Remove(s.ID)
+}
+
+func (m *SnippetModel) DeleteExpired(ctx context.Context, limit int) (int, error) {
+	m.DB.mu.Lock()
+	defer m.DB.mu.Unlock()
+
+	n := 0
+	now := m.DB.now()
+	for _, s := range m.DB.snippets {
+		if n == limit {
+			break
+		}
+		if s.Expires.After(now) {
+			continue
+		}
+		m.DB.deleteSnippet(s)
+		n++
+	}
+	return n, nil
+}
+
+// Find filters every live snippet, sorts what's left and starts the page
+// after the cursor, the same way the SQL backends do with WHERE and ORDER BY.
+func (m *SnippetModel) Find(ctx context.Context, q models.ListQuery) (*models.SnippetPage, error) {
+	cursor, after, err := q.Cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	m.DB.mu.RLock()
+	defer m.DB.mu.RUnlock()
+
+	// compare orders snippets the way q is sorted.
+	compare := func(a, b *models.Snippet) int { return cmp.Compare(b.ID, a.ID) }
+	switch q.Order() {
+	case models.SortOldest:
+		compare = func(a, b *models.Snippet) int { return cmp.Compare(a.ID, b.ID) }
+	case models.SortTitle:
+		// Without case, like MySQL's collation.
+		compare = func(a, b *models.Snippet) int {
+			return cmp.Or(cmp.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title)), cmp.Compare(a.ID, b.ID))
+		}
+	}
+	last := &models.Snippet{ID: cursor.ID, Title: cursor.Title}
+
+	found := []*models.Snippet{}
+	for id := range m.DB.snippets {
+		s, ok := m.DB.live(id)
+		switch {
+		case !ok,
+			!listed(s, q.ViewerID),
+			q.AuthorID != 0 && s.UserID != q.AuthorID,
+			q.Tag != "" && !slices.Contains(s.Tags, q.Tag),
+			q.Language != "" && s.Language != q.Language,
+			!q.From.IsZero() && s.Created.Before(q.From),
+			!q.To.IsZero() && !s.Created.Before(q.To),
+			after && compare(s, last) <= 0:
+			continue
+		}
+		found = append(found, s)
+	}
+	slices.SortFunc(found, compare)
+
+	snippets := []*models.Snippet{}
+	for _, s := range found[:min(len(found), q.Limit+1)] {
+		snippets = append(snippets, m.DB.snippetCopy(s))
+	}
+	return models.NewSnippetPage(q, snippets), nil
+}
@@ -0,0 +1,21 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	db := NewDB()
+	storetest.Run(t, &storetest.Stores{
+		Snippets: &SnippetModel{DB: db, Cost: 4},
+		Users:    &UserModel{DB: db, Cost: 4},
+		Expire: func(t *testing.T, id int) {
+			db.mu.Lock()
+			defer db.mu.Unlock()
+			db.snippets[id].Expires = db.now().Add(-time.Minute)
+		},
+	})
+}
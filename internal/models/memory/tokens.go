@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"slices"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+)
+
+type TokenModel struct {
+	DB *DB
+}
+
+var _ models.TokenModelInterface = (*TokenModel)(nil)
+
+func (m *TokenModel) Insert(ctx context.Context, userID int, name string, expires int) (string, error) {
+	plaintext, hash, err := models.NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	m.DB.mu.Lock()
+	defer m.DB.mu.Unlock()
+
+	now := m.DB.now()
+	m.DB.lastTokenID++
+	t := &token{
+		Token: models.Token{
+			ID:      m.DB.lastTokenID,
+			UserID:  userID,
+			Name:    name,
+			Created: now,
+		},
+		hash: hash,
+	}
+	if expires > 0 {
+		t.Expires = now.AddDate(0, 0, expires)
+	}
+	m.DB.tokens[t.ID] = t
+	return plaintext, nil
+}
+
+func (m *TokenModel) All(ctx context.Context, userID int) ([]*models.Token, error) {
+	m.DB.mu.RLock()
+	defer m.DB.mu.RUnlock()
+
+	tokens := []*models.Token{}
+	for _, t := range m.DB.tokens {
+		if t.UserID == userID {
+			c := t.Token
+			tokens = append(tokens, &c)
+		}
+	}
+	slices.SortFunc(tokens, func(a, b *models.Token) int { return b.ID - a.ID })
+	return tokens, nil
+}
+
+func (m *TokenModel) Delete(ctx context.Context, id, userID int) error {
+	m.DB.mu.Lock()
+	defer m.DB.mu.Unlock()
+
+	t, ok := m.DB.tokens[id]
+	if !ok || t.UserID != userID {
+		return models.ErrNoRecord
+	}
+	delete(m.DB.tokens, id)
+	return nil
+}
+
+func (m *TokenModel) Authenticate(ctx context.Context, plaintext string) (int, error) {
+	hash := models.HashToken(plaintext)
+
+	m.DB.mu.Lock()
+	defer m.DB.mu.Unlock()
+
+	now := m.DB.now()
+	for _, t := range m.DB.tokens {
+		if !bytes.Equal(t.hash, hash) {
+			continue
+		}
+		if !t.Expires.IsZero() && !t.Expires.After(now) {
+			break
+		}
+		t.LastUsed = now
+		return t.UserID, nil
+	}
+	return 0, models.ErrInvalidCredentials
+}
@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type UserModel struct {
+	DB   *DB
+	Cost int // bcrypt cost for new passwords; zero means models.DefaultBcryptCost
+}
+
+var _ models.UserModelInterface = (*UserModel)(nil)
+
+// Insert adds a user, enforcing the same unique email rule as the
+// users_uc_email constraint in MySQL, where emails differing only in case are
+// the same.
+func (m *UserModel) Insert(ctx context.Context, name, email, password string) error {
+	hashedPassword, err := models.HashPassword(password, m.Cost)
+	if err != nil {
+		return err
+	}
+
+	m.DB.mu.Lock()
+	defer m.DB.mu.Unlock()
+
+	for _, u := range m.DB.users {
+		if strings.EqualFold(u.Email, email) {
+			return models.ErrDuplicateEmail
+		}
+	}
+	m.DB.lastUserID++
+	m.DB.users[m.DB.lastUserID] = &models.User{
+		ID:             m.DB.lastUserID,
+		Name:           name,
+		Email:          email,
+		HashedPassword: hashedPassword,
+		Created:        m.DB.now(),
+	}
+	return nil
+}
+
+func (m *UserModel) Authenticate(ctx context.Context, email, password string) (int, error) {
+	m.DB.mu.RLock()
+	var user *models.User
+	for _, u := range m.DB.users {
+		if strings.EqualFold(u.Email, email) {
+			user = u
+			break
+		}
+	}
+	m.DB.mu.RUnlock()
+	if user == nil {
+		return 0, models.ErrInvalidCredentials
+	}
+
+	err := bcrypt.CompareHashAndPassword(user.HashedPassword, []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return 0, models.ErrInvalidCredentials
+		}
+		return 0, err
+	}
+	return user.ID, nil
+}
+
+func (m *UserModel) Exists(ctx context.Context, id int) (bool, error) {
+	m.DB.mu.RLock()
+	defer m.DB.mu.RUnlock()
+
+	_, ok := m.DB.users[id]
+	return ok, nil
+}
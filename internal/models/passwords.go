@@ -0,0 +1,40 @@
+package models
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+/*
+A snippet can have a password, for sharing it with people who don't have an account. Like the
+passwords of users it's only stored hashed with bcrypt, and the snippet is then made unlisted, as
+listings and search results show the content of the snippets in them.
+*/
+
+// MaxSnippetPasswordLength is the most bcrypt can hash, in bytes.
+const MaxSnippetPasswordLength = 72
+
+// HashSnippetPassword hashes the password of a new snippet like HashPassword,
+// or returns nil if there isn't one.
+func HashSnippetPassword(password string, cost int) ([]byte, error) {
+	if password == "" {
+		return nil, nil
+	}
+	return HashPassword(password, cost)
+}
+
+// Protected reports whether the snippet needs a password to be read. Only
+// snippets returned by Get and GetBySlug know.
+func (s *Snippet) Protected() bool {
+	return len(s.HashedPassword) > 0
+}
+
+// PasswordMatches reports whether password is the one the snippet was given.
+func (s *Snippet) PasswordMatches(password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword(s.HashedPassword, []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+)
+
+type RevisionModel struct {
+	DB *sql.DB
+}
+
+var _ models.RevisionModelInterface = (*RevisionModel)(nil)
+
+func (m *RevisionModel) All(ctx context.Context, snippetID int) ([]*models.Revision, error) {
+	stmt := `SELECT r.id, r.snippet_id, r.version, r.user_id, u.name, r.title, r.content, r.created
+FROM snippet_revisions r INNER JOIN users u ON u.id = r.user_id
+WHERE r.snippet_id = $1 ORDER BY r.version DESC`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*models.Revision{}
+	for rows.Next() {
+		r := &models.Revision{}
+		err = rows.Scan(&r.ID, &r.SnippetID, &r.Version, &r.UserID, &r.Author, &r.Title, &r.Content, &r.Created)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (m *RevisionModel) Get(ctx context.Context, snippetID, version int) (*models.Revision, error) {
+	stmt := `SELECT r.id, r.snippet_id, r.version, r.user_id, u.name, r.title, r.content, r.created
+FROM snippet_revisions r INNER JOIN users u ON u.id = r.user_id
+WHERE r.snippet_id = $1 AND r.version = $2`
+
+	r := &models.Revision{}
+	err := m.DB.QueryRowContext(ctx, stmt, snippetID, version).Scan(&r.ID, &r.SnippetID, &r.Version, &r.UserID, &r.Author, &r.Title, &r.Content, &r.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+// insertRevision appends the next version of a snippet inside tx.
+func insertRevision(ctx context.Context, tx *sql.Tx, snippetID, userID int, title, content string) error {
+	stmt := `INSERT INTO snippet_revisions (snippet_id, version, user_id, title, content, created)
+SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, NOW()
+FROM snippet_revisions WHERE snippet_id = $1`
+
+	_, err := tx.ExecContext(ctx, stmt, snippetID, userID, title, content)
+	return err
+}
@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+	"github.com/Vanshikav123/ByteFlow.git/internal/search"
+)
+
+// SearchModel searches snippets with the generated search tsvector column and
+// its GIN index.
+type SearchModel struct {
+	DB *sql.DB
+}
+
+var _ models.SearchModelInterface = (*SearchModel)(nil)
+
+// Search runs the terms as a prefix tsquery, "term1:* & term2:*", with the
+// simple configuration so code isn't stemmed or stripped of stopwords, and
+// ranks with ts_rank. The title is weighted A and the content B.
+func (m *SearchModel) Search(ctx context.Context, query string, viewerID, limit, offset int) ([]*models.SearchResult, error) {
+	terms := search.Terms(query)
+	if len(terms) == 0 {
+		return []*models.SearchResult{}, nil
+	}
+	tsquery := strings.Join(terms, ":* & ") + ":*"
+
+	stmt := `SELECT s.id, s.slug, s.user_id, u.name, s.title, s.content, s.language, s.visibility, s.max_views, s.views, s.created, s.expires, ts_rank(s.search, q) AS score
+FROM snippets s INNER JOIN users u ON u.id = s.user_id, to_tsquery('simple', $1) q
+WHERE s.search @@ q AND s.expires > NOW() AND (s.visibility = 'public' OR s.user_id = $2)
+ORDER BY score DESC, s.id DESC LIMIT $3 OFFSET $4`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, tsquery, viewerID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*models.SearchResult{}
+	snippets := []*models.Snippet{}
+	for rows.Next() {
+		s := &models.Snippet{}
+		var score float64
+		err = rows.Scan(&s.ID, &s.Slug, &s.UserID, &s.Author, &s.Title, &s.Content, &s.Language, &s.Visibility, &s.MaxViews, &s.Views, &s.Created, &s.Expires, &score)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, models.NewSearchResult(s, score, terms))
+		snippets = append(snippets, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if err = loadTags(ctx, m.DB, snippets); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
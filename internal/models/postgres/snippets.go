@@ -0,0 +1,369 @@
+// Package postgres implements the models store interfaces on PostgreSQL
+// through the pgx database/sql driver. Timestamps are timestamptz columns
+// computed by the server with NOW().
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+)
+
+type SnippetModel struct {
+	DB   *sql.DB
+	Cost int // bcrypt cost for snippet passwords; zero means models.DefaultBcryptCost
+}
+
+var _ models.SnippetModelInterface = (*SnippetModel)(nil)
+
+func (m *SnippetModel) Insert(ctx context.Context, userID int, f models.SnippetFields) (int, string, error) {
+	slug, err := models.NewSlug()
+	if err != nil {
+		return 0, "", err
+	}
+	hashedPassword, err := models.HashSnippetPassword(f.Password, m.Cost)
+	if err != nil {
+		return 0, "", err
+	}
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback()
+
+	// Postgres drivers don't support LastInsertId, so the id comes back
+	// from a RETURNING clause instead.
+	stmt := `INSERT INTO snippets (slug, user_id, title, content, language, visibility, max_views, hashed_password, created, expires)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW() + make_interval(days => $9))
+RETURNING id`
+
+	var id int
+	err = tx.QueryRowContext(ctx, stmt, slug, userID, f.Title, f.Content, f.Language, f.Visibility, f.MaxViews, hashedPassword, f.Expires).Scan(&id)
+	if err != nil {
+		return 0, "", err
+	}
+
+	err = insertRevision(ctx, tx, id, userID, f.Title, f.Content)
+	if err != nil {
+		return 0, "", err
+	}
+	err = insertTags(ctx, tx, id, f.Tags)
+	if err != nil {
+		return 0, "", err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, "", err
+	}
+	return id, slug, nil
+}
+
+func (m *SnippetModel) Get(ctx context.Context, id int) (*models.Snippet, error) {
+	return get(ctx, m.DB, "s.id = $1", id)
+}
+
+func (m *SnippetModel) GetBySlug(ctx context.Context, slug string) (*models.Snippet, error) {
+	return get(ctx, m.DB, "s.slug = $1", slug)
+}
+
+func get(ctx context.Context, db querier, cond string, arg any) (*models.Snippet, error) {
+	stmt := `SELECT s.id, s.slug, s.user_id, u.name, s.title, s.content, s.language, s.visibility, s.max_views, s.views, s.hashed_password, s.created, s.expires
+FROM snippets s INNER JOIN users u ON u.id = s.user_id
+WHERE s.expires > NOW() AND ` + cond
+
+	s := &models.Snippet{}
+	err := db.QueryRowContext(ctx, stmt, arg).Scan(&s.ID, &s.Slug, &s.UserID, &s.Author, &s.Title, &s.Content, &s.Language, &s.Visibility, &s.MaxViews, &s.Views, &s.HashedPassword, &s.Created, &s.Expires)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		}
+		return nil, err
+	}
+	if err = loadTags(ctx, db, []*models.Snippet{s}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (m *SnippetModel) LegacySlug(ctx context.Context, id int) (string, error) {
+	stmt := `SELECT slug FROM snippets
+WHERE id = $1 AND legacy AND visibility = 'public' AND expires > NOW()`
+
+	var slug string
+	err := m.DB.QueryRowContext(ctx, stmt, id).Scan(&slug)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", models.ErrNoRecord
+	}
+	return slug, err
+}
+
+func (m *SnippetModel) Consume(ctx context.Context, id int) (*models.Snippet, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt := `UPDATE snippets SET views = views + 1
+WHERE id = $1 AND max_views > 0 AND views < max_views AND expires > NOW()`
+
+	result, err := tx.ExecContext(ctx, stmt, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = requireRow(result); err != nil {
+		return nil, err
+	}
+	s, err := get(ctx, tx, "s.id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if s.Views >= s.MaxViews {
+		if _, err = tx.ExecContext(ctx, `DELETE FROM snippets WHERE id = $1`, id); err != nil {
+			return nil, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (m *SnippetModel) Update(ctx context.Context, id, userID int, f models.SnippetFields) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `UPDATE snippets SET title = $1, content = $2, language = $3, visibility = $4,
+    expires = NOW() + make_interval(days => $5)
+WHERE id = $6`
+
+	_, err = tx.ExecContext(ctx, stmt, f.Title, f.Content, f.Language, f.Visibility, f.Expires, id)
+	if err != nil {
+		return err
+	}
+	err = insertRevision(ctx, tx, id, userID, f.Title, f.Content)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM snippet_tags WHERE snippet_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	err = insertTags(ctx, tx, id, f.Tags)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *SnippetModel) Restore(ctx context.Context, id, userID, version int) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var title, content string
+	stmt := `SELECT title, content FROM snippet_revisions WHERE snippet_id = $1 AND version = $2`
+	err = tx.QueryRowContext(ctx, stmt, id, version).Scan(&title, &content)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrNoRecord
+		}
+		return err
+	}
+
+	stmt = `UPDATE snippets SET title = $1, content = $2 WHERE id = $3`
+	_, err = tx.ExecContext(ctx, stmt, title, content, id)
+	if err != nil {
+		return err
+	}
+	err = insertRevision(ctx, tx, id, userID, title, content)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *SnippetModel) Delete(ctx context.Context, id int) error {
+	stmt := `DELETE FROM snippets WHERE id = $1`
+
+	result, err := m.DB.ExecContext(ctx, stmt, id)
+	if err != nil {
+		return err
+	}
+	return requireRow(result)
+}
+
+// Find pages on the id, or on (title, id) when sorting by title. The
+// placeholders are numbered as the filters that are set add their arguments.
+func (m *SnippetModel) Find(ctx context.Context, q models.ListQuery) (*models.SnippetPage, error) {
+	cursor, after, err := q.Cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	args := []any{}
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	where := []string{"s.expires > NOW()"}
+	if q.ViewerID != 0 {
+		where = append(where, "(s.visibility = 'public' OR s.user_id = "+arg(q.ViewerID)+")")
+	} else {
+		where = append(where, "s.visibility = 'public'")
+	}
+	if q.AuthorID != 0 {
+		where = append(where, "s.user_id = "+arg(q.AuthorID))
+	}
+	if q.Tag != "" {
+		where = append(where, "EXISTS (SELECT 1 FROM snippet_tags t WHERE t.snippet_id = s.id AND t.tag = "+arg(q.Tag)+")")
+	}
+	if q.Language != "" {
+		where = append(where, "s.language = "+arg(q.Language))
+	}
+	if !q.From.IsZero() {
+		where = append(where, "s.created >= "+arg(q.From))
+	}
+	if !q.To.IsZero() {
+		where = append(where, "s.created < "+arg(q.To))
+	}
+
+	var order string
+	switch q.Order() {
+	case models.SortOldest:
+		order = "s.id ASC"
+		if after {
+			where = append(where, "s.id > "+arg(cursor.ID))
+		}
+	case models.SortTitle:
+		order = "s.title ASC, s.id ASC"
+		if after {
+			where = append(where, "(s.title, s.id) > ("+arg(cursor.Title)+", "+arg(cursor.ID)+")")
+		}
+	default:
+		order = "s.id DESC"
+		if after {
+			where = append(where, "s.id < "+arg(cursor.ID))
+		}
+	}
+
+	stmt := `SELECT s.id, s.slug, s.user_id, u.name, s.title, s.content, s.language, s.visibility, s.max_views, s.views, s.created, s.expires
+FROM snippets s INNER JOIN users u ON u.id = s.user_id
+WHERE ` + strings.Join(where, " AND ") + `
+ORDER BY ` + order + ` LIMIT ` + arg(q.Limit+1)
+
+	snippets, err := m.query(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewSnippetPage(q, snippets), nil
+}
+
+// DeleteExpired removes up to limit expired snippets. There's no DELETE ...
+// LIMIT here, so the rows are picked by a subquery.
+func (m *SnippetModel) DeleteExpired(ctx context.Context, limit int) (int, error) {
+	stmt := `DELETE FROM snippets WHERE id IN (
+SELECT id FROM snippets WHERE expires <= NOW() ORDER BY expires LIMIT $1)`
+
+	result, err := m.DB.ExecContext(ctx, stmt, limit)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+func (m *SnippetModel) query(ctx context.Context, stmt string, args ...any) ([]*models.Snippet, error) {
+	rows, err := m.DB.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snippets := []*models.Snippet{}
+	for rows.Next() {
+		s := &models.Snippet{}
+		err = rows.Scan(&s.ID, &s.Slug, &s.UserID, &s.Author, &s.Title, &s.Content, &s.Language, &s.Visibility, &s.MaxViews, &s.Views, &s.Created, &s.Expires)
+		if err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if err = loadTags(ctx, m.DB, snippets); err != nil {
+		return nil, err
+	}
+	return snippets, nil
+}
+
+// requireRow returns models.ErrNoRecord if a statement did not affect any rows.
+func requireRow(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrNoRecord
+	}
+	return nil
+}
+
+// querier is what *sql.DB and *sql.Tx have in common, so that the same
+// queries can be run in a transaction or outside of one.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func insertTags(ctx context.Context, tx *sql.Tx, snippetID int, tags []string) error {
+	for _, tag := range tags {
+		_, err := tx.ExecContext(ctx, `INSERT INTO snippet_tags (snippet_id, tag) VALUES($1, $2)`, snippetID, tag)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTags fills in the Tags of snippets with a single query, passing the
+// ids as one array parameter.
+func loadTags(ctx context.Context, db querier, snippets []*models.Snippet) error {
+	if len(snippets) == 0 {
+		return nil
+	}
+	byID := make(map[int]*models.Snippet, len(snippets))
+	ids := make([]int64, len(snippets))
+	for i, s := range snippets {
+		s.Tags = []string{}
+		byID[s.ID] = s
+		ids[i] = int64(s.ID)
+	}
+
+	stmt := `SELECT snippet_id, tag FROM snippet_tags WHERE snippet_id = ANY($1) ORDER BY tag`
+
+	rows, err := db.QueryContext(ctx, stmt, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var tag string
+		if err = rows.Scan(&id, &tag); err != nil {
+			return err
+		}
+		byID[id].Tags = append(byID[id].Tags, tag)
+	}
+	return rows.Err()
+}
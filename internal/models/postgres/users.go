@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// uniqueViolation is the SQLSTATE Postgres uses when a unique constraint fails.
+const uniqueViolation = "23505"
+
+type UserModel struct {
+	DB   *sql.DB
+	Cost int // bcrypt cost for new passwords; zero means models.DefaultBcryptCost
+}
+
+var _ models.UserModelInterface = (*UserModel)(nil)
+
+func (m *UserModel) Insert(ctx context.Context, name, email, password string) error {
+	hashedPassword, err := models.HashPassword(password, m.Cost)
+	if err != nil {
+		return err
+	}
+	stmt := `INSERT INTO users (name, email, hashed_password, created)
+VALUES($1, $2, $3, NOW())`
+
+	_, err = m.DB.ExecContext(ctx, stmt, name, email, string(hashedPassword))
+	if err != nil {
+		// Postgres reports the violated constraint by name, so there's no
+		// need to match on the message like the MySQL model does.
+		var pgError *pgconn.PgError
+		if errors.As(err, &pgError) {
+			if pgError.Code == uniqueViolation && pgError.ConstraintName == "users_uc_email" {
+				return models.ErrDuplicateEmail
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func (m *UserModel) Authenticate(ctx context.Context, email, password string) (int, error) {
+	var id int
+	var hashedPassword []byte
+
+	// Emails match whatever their case, see the users_uc_email index.
+	stmt := "SELECT id, hashed_password FROM users WHERE lower(email) = lower($1)"
+	err := m.DB.QueryRowContext(ctx, stmt, email).Scan(&id, &hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, models.ErrInvalidCredentials
+		}
+		return 0, err
+	}
+
+	err = bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return 0, models.ErrInvalidCredentials
+		}
+		return 0, err
+	}
+	return id, nil
+}
+
+func (m *UserModel) Exists(ctx context.Context, id int) (bool, error) {
+	var exists bool
+
+	stmt := "SELECT EXISTS(SELECT true FROM users WHERE id = $1)"
+	err := m.DB.QueryRowContext(ctx, stmt, id).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// RevisionModelInterface gives read access to snippet history. Revisions
+// are written by the snippet store itself.
+type RevisionModelInterface interface {
+	All(ctx context.Context, snippetID int) ([]*Revision, error)
+	Get(ctx context.Context, snippetID, version int) (*Revision, error)
+}
+
+// Revision is an immutable copy of a snippet's title and content, written
+// every time the snippet is created, edited or restored. Version counts up
+// from 1 for each snippet and UserID/Author record who made the change.
+type Revision struct {
+	ID        int       `json:"id"`
+	SnippetID int       `json:"snippet_id"`
+	Version   int       `json:"version"`
+	UserID    int       `json:"user_id"`
+	Author    string    `json:"author"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Created   time.Time `json:"created"`
+}
+
+type RevisionModel struct {
+	DB *sql.DB
+}
+
+// All returns every revision of a snippet, newest first.
+func (m *RevisionModel) All(ctx context.Context, snippetID int) ([]*Revision, error) {
+	stmt := `SELECT r.id, r.snippet_id, r.version, r.user_id, u.name, r.title, r.content, r.created
+FROM snippet_revisions r INNER JOIN users u ON u.id = r.user_id
+WHERE r.snippet_id = ? ORDER BY r.version DESC`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*Revision{}
+	for rows.Next() {
+		r := &Revision{}
+		err = rows.Scan(&r.ID, &r.SnippetID, &r.Version, &r.UserID, &r.Author, &r.Title, &r.Content, &r.Created)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// Get returns a single version of a snippet.
+func (m *RevisionModel) Get(ctx context.Context, snippetID, version int) (*Revision, error) {
+	stmt := `SELECT r.id, r.snippet_id, r.version, r.user_id, u.name, r.title, r.content, r.created
+FROM snippet_revisions r INNER JOIN users u ON u.id = r.user_id
+WHERE r.snippet_id = ? AND r.version = ?`
+
+	r := &Revision{}
+	err := m.DB.QueryRowContext(ctx, stmt, snippetID, version).Scan(&r.ID, &r.SnippetID, &r.Version, &r.UserID, &r.Author, &r.Title, &r.Content, &r.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+// insertRevision appends the next version of a snippet inside tx. It is called
+// by SnippetModel whenever the current title or content changes, so that the
+// snippets row and its history can never disagree.
+func insertRevision(ctx context.Context, tx *sql.Tx, snippetID, userID int, title, content string) error {
+	stmt := `INSERT INTO snippet_revisions (snippet_id, version, user_id, title, content, created)
+SELECT ?, COALESCE(MAX(version), 0) + 1, ?, ?, ?, UTC_TIMESTAMP()
+FROM snippet_revisions WHERE snippet_id = ?`
+
+	_, err := tx.ExecContext(ctx, stmt, snippetID, userID, title, content, snippetID)
+	return err
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/search"
+)
+
+// SearchModelInterface finds snippets by the words in their title and
+// content. Every backend implements it with its own full-text index:
+// SearchModel uses a MySQL FULLTEXT index.
+type SearchModelInterface interface {
+	// Search returns up to limit live snippets matching query, best match
+	// first, skipping the first offset of them. The query is split into
+	// terms by search.Terms, and a query without any matches nothing. Only
+	// public snippets and those of viewerID, if it isn't 0, are searched.
+	Search(ctx context.Context, query string, viewerID, limit, offset int) ([]*SearchResult, error)
+}
+
+// SearchResult is a snippet found by a search, with the matching words of its
+// title and content highlighted. Score is higher for better matches; it only
+// means something compared to the other results of the same search.
+type SearchResult struct {
+	Snippet  *Snippet          `json:"snippet"`
+	Score    float64           `json:"score"`
+	Title    search.Fragment   `json:"title"`
+	Excerpts []search.Fragment `json:"excerpts"`
+}
+
+// How much of the content each result shows around the matches.
+const (
+	excerptWidth = 160
+	excerptCount = 3
+)
+
+// NewSearchResult highlights the terms in the title and content of s. Every
+// backend builds its results with it so they all look the same.
+func NewSearchResult(s *Snippet, score float64, terms []string) *SearchResult {
+	return &SearchResult{
+		Snippet:  s,
+		Score:    score,
+		Title:    search.Highlight(s.Title, terms),
+		Excerpts: search.Excerpts(s.Content, terms, excerptWidth, excerptCount),
+	}
+}
+
+// SearchModel searches snippets with the FULLTEXT indexes on their title and
+// content.
+type SearchModel struct {
+	DB *sql.DB
+}
+
+/*
+Search runs the terms as a boolean mode query, "+term1* +term2*", so every term must start a word,
+and ranks by relevance with title matches counting double. InnoDB only indexes words of at least
+innodb_ft_min_token_size (3) characters and skips its stopwords, so those can't be found.
+*/
+func (m *SearchModel) Search(ctx context.Context, query string, viewerID, limit, offset int) ([]*SearchResult, error) {
+	terms := search.Terms(query)
+	if len(terms) == 0 {
+		return []*SearchResult{}, nil
+	}
+	match := "+" + strings.Join(terms, "* +") + "*"
+
+	stmt := `SELECT s.id, s.slug, s.user_id, u.name, s.title, s.content, s.language, s.visibility, s.max_views, s.views, s.created, s.expires,
+    MATCH(s.title) AGAINST(? IN BOOLEAN MODE) + MATCH(s.title, s.content) AGAINST(? IN BOOLEAN MODE) AS score
+FROM snippets s INNER JOIN users u ON u.id = s.user_id
+WHERE MATCH(s.title, s.content) AGAINST(? IN BOOLEAN MODE) AND s.expires > UTC_TIMESTAMP()
+    AND (s.visibility = 'public' OR s.user_id = ?)
+ORDER BY score DESC, s.id DESC LIMIT ? OFFSET ?`
+
+	// No user has id 0, so an anonymous viewer only matches public snippets.
+	return m.query(ctx, terms, stmt, match, match, match, viewerID, limit, offset)
+}
+
+// query runs a statement returning the snippet columns followed by a score,
+// and builds the results from its rows.
+func (m *SearchModel) query(ctx context.Context, terms []string, stmt string, args ...any) ([]*SearchResult, error) {
+	rows, err := m.DB.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*SearchResult{}
+	snippets := []*Snippet{}
+	for rows.Next() {
+		s := &Snippet{}
+		var score float64
+		err = rows.Scan(&s.ID, &s.Slug, &s.UserID, &s.Author, &s.Title, &s.Content, &s.Language, &s.Visibility, &s.MaxViews, &s.Views, &s.Created, &s.Expires, &score)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, NewSearchResult(s, score, terms))
+		snippets = append(snippets, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if err = loadTags(ctx, m.DB, snippets); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
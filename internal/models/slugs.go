@@ -0,0 +1,24 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+)
+
+/*
+Snippets are addressed by a random slug rather than their id. Ids count up, so anyone could read
+every unlisted snippet by trying each number in turn; 72 random bits can't be guessed like that.
+The id stays the primary key and what other tables refer to, but is never shown.
+
+Snippets that existed before slugs were added are "legacy" ones: their numeric URLs may have been
+shared, so LegacySlug still finds them by id, as long as they're public.
+*/
+
+// NewSlug generates the slug of a new snippet: 12 URL-safe base64 characters.
+func NewSlug() (string, error) {
+	b := make([]byte, 9)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
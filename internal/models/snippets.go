@@ -1,8 +1,10 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 )
 
@@ -15,33 +17,99 @@ method=> A method in Go is a function that operates on a specific type (struct)
 tied to one type
 */
 
+// SnippetModelInterface is what the web application needs from a snippet
+// store. SnippetModel implements it on top of MySQL.
+type SnippetModelInterface interface {
+	// Insert returns the id and slug of the new snippet.
+	Insert(ctx context.Context, userID int, f SnippetFields) (int, string, error)
+	Get(ctx context.Context, id int) (*Snippet, error)
+	GetBySlug(ctx context.Context, slug string) (*Snippet, error)
+	// LegacySlug returns the slug of the live public snippet that had the
+	// numeric URL id before snippets had slugs, or ErrNoRecord.
+	LegacySlug(ctx context.Context, id int) (string, error)
+	// Consume counts a view of a snippet with a view limit, deleting it after
+	// the last one.
+	Consume(ctx context.Context, id int) (*Snippet, error)
+	Update(ctx context.Context, id, userID int, f SnippetFields) error
+	Restore(ctx context.Context, id, userID, version int) error
+	Delete(ctx context.Context, id int) error
+	// Find returns a page of the live snippets matching q. The home page, the
+	// user's own snippets, the API and the feed are all listed with it.
+	Find(ctx context.Context, q ListQuery) (*SnippetPage, error)
+	DeleteExpired(ctx context.Context, limit int) (int, error)
+}
+
 // snippet struct to store paramaters of snippets
 type Snippet struct {
-	ID      int
-	Title   string
-	Content string
-	Created time.Time
-	Expires time.Time
+	ID         int        `json:"-"`       // internal, see NewSlug
+	Slug       string     `json:"id"`      // what the snippet is addressed by
+	UserID     int        `json:"user_id"` // id of the user who created the snippet
+	Author     string     `json:"author"`  // name of that user, joined from the users table
+	Title      string     `json:"title"`
+	Content    string     `json:"content"`
+	Language   string     `json:"language"` // one of Languages, or "" if not given
+	Visibility Visibility `json:"visibility"`
+	MaxViews   int        `json:"max_views"` // 0 for no limit, see ViewsLeft
+	// HashedPassword is the bcrypt hash of the password readers need, or nil.
+	// Only Get and GetBySlug fill it in.
+	HashedPassword []byte    `json:"-"`
+	Views          int       `json:"views"` // views counted towards MaxViews
+	Tags           []string  `json:"tags"`  // sorted, never nil
+	Created        time.Time `json:"created"`
+	Expires        time.Time `json:"expires"`
+}
+
+// SnippetFields are what the author of a snippet sets when creating or editing
+// it. Tags must already be normalised by NormalizeTags, and Expires is the
+// number of days from now the snippet expires in. MaxViews and Password, which
+// is "" for none, are only set when the snippet is created; Update leaves them
+// as they are.
+type SnippetFields struct {
+	Title      string
+	Content    string
+	Language   string
+	Tags       []string
+	Visibility Visibility
+	MaxViews   int
+	Password   string
+	Expires    int
 }
 
 // database model
 type SnippetModel struct {
-	DB *sql.DB
+	DB   *sql.DB
+	Cost int // bcrypt cost for snippet passwords; zero means DefaultBcryptCost
 }
 
 // insert ,get and latest methods interact with database to store snippets of text
 // This is a method of SnippetModel, meaning it operates on an instance of SnippetModel.
-// m.DB.Exec(...) executes the SQL statement.
+// m.DB.ExecContext(ctx, ...) executes the SQL statement.
 // result is of type sql.Result, which contains metadata about the executed query.
-func (m *SnippetModel) Insert(title string, content string, expires int) (int, error) {
-	stmt := `INSERT INTO snippets (title, content, created, expires)
-VALUES(?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
+func (m *SnippetModel) Insert(ctx context.Context, userID int, f SnippetFields) (int, string, error) {
+	slug, err := NewSlug()
+	if err != nil {
+		return 0, "", err
+	}
+	hashedPassword, err := HashSnippetPassword(f.Password, m.Cost)
+	if err != nil {
+		return 0, "", err
+	}
+	// The snippet and its first revision are written in one transaction so a
+	// snippet never exists without any history.
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback()
+
+	stmt := `INSERT INTO snippets (slug, user_id, title, content, language, visibility, max_views, hashed_password, created, expires)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
 	// Exec is a method from Go’s database/sql package used to execute SQL statements that do not return rows.
 	//It's used for INSERT, UPDATE, DELETE, and other statements that modify data.
-	result, err := m.DB.Exec(stmt, title, content, expires)
+	result, err := tx.ExecContext(ctx, stmt, slug, userID, f.Title, f.Content, f.Language, f.Visibility, f.MaxViews, hashedPassword, f.Expires)
 
 	if err != nil {
-		return 0, err
+		return 0, "", err
 	}
 	//  LastInsertId() retrieves the ID of the last inserted row.
 	/*LastInsertId() retrieves the ID of the most recently inserted row in a table with an auto-incrementing primary key.*/
@@ -51,20 +119,42 @@ VALUES(?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
 	Query()	Executes a query that returns multiple rows.*/
 	id, err := result.LastInsertId()
 	if err != nil {
-		return 0, err
+		return 0, "", err
+	}
+
+	err = insertRevision(ctx, tx, int(id), userID, f.Title, f.Content)
+	if err != nil {
+		return 0, "", err
+	}
+	err = insertTags(ctx, tx, int(id), f.Tags)
+	if err != nil {
+		return 0, "", err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, "", err
 	}
 	// The id (which is of type int64) is converted to int and returned.
-	return int(id), nil
+	return int(id), slug, nil
+}
+
+func (m *SnippetModel) Get(ctx context.Context, id int) (*Snippet, error) {
+	return get(ctx, m.DB, "s.id = ?", id)
+}
+
+func (m *SnippetModel) GetBySlug(ctx context.Context, slug string) (*Snippet, error) {
+	return get(ctx, m.DB, "s.slug = ?", slug)
 }
 
-func (m *SnippetModel) Get(id int) (*Snippet, error) {
-	stmt := `SELECT id, title, content, created, expires FROM snippets
-WHERE expires > UTC_TIMESTAMP() AND id = ?`
+// get returns the live snippet matching a condition on one column.
+func get(ctx context.Context, db querier, cond string, arg any) (*Snippet, error) {
+	stmt := `SELECT s.id, s.slug, s.user_id, u.name, s.title, s.content, s.language, s.visibility, s.max_views, s.views, s.hashed_password, s.created, s.expires
+FROM snippets s INNER JOIN users u ON u.id = s.user_id
+WHERE s.expires > UTC_TIMESTAMP() AND ` + cond
 
-	row := m.DB.QueryRow(stmt, id)
+	row := db.QueryRowContext(ctx, stmt, arg)
 	/*This creates a new Snippet struct on the heap and stores its memory address in s.
 	  s is a pointer to a Snippet (*Snippet).
-	  Since Get returns *Snippet, using a pointer allows efficient memory handling (we avoid copying the entire struct).*/
+	  Since get returns *Snippet, using a pointer allows efficient memory handling (we avoid copying the entire struct).*/
 	s := &Snippet{}
 	/*Scan fills variables with values from the SQL query.
 	  Why &? Because Scan needs pointers to modify s.ID, s.Title, etc.
@@ -76,7 +166,7 @@ WHERE expires > UTC_TIMESTAMP() AND id = ?`
 	Row.Next()	Moves to the next row in a multi-row result.
 	Row.Err()	Checks for errors in row iteration.
 	*/
-	err := row.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+	err := row.Scan(&s.ID, &s.Slug, &s.UserID, &s.Author, &s.Title, &s.Content, &s.Language, &s.Visibility, &s.MaxViews, &s.Views, &s.HashedPassword, &s.Created, &s.Expires)
 	if err != nil {
 
 		if errors.Is(err, sql.ErrNoRows) {
@@ -85,26 +175,253 @@ WHERE expires > UTC_TIMESTAMP() AND id = ?`
 			return nil, err
 		}
 	}
+	if err = loadTags(ctx, db, []*Snippet{s}); err != nil {
+		return nil, err
+	}
 	// If everything went OK then return the Snippet object.
 	return s, nil
 }
-func (m *SnippetModel) Latest() ([]*Snippet, error) {
-	stmt := `SELECT id, title, content, created, expires FROM snippets
-WHERE expires > UTC_TIMESTAMP() ORDER BY id DESC LIMIT 10`
 
-	rows, err := m.DB.Query(stmt)
+func (m *SnippetModel) LegacySlug(ctx context.Context, id int) (string, error) {
+	stmt := `SELECT slug FROM snippets
+WHERE id = ? AND legacy AND visibility = 'public' AND expires > UTC_TIMESTAMP()`
+
+	var slug string
+	err := m.DB.QueryRowContext(ctx, stmt, id).Scan(&slug)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNoRecord
+	}
+	return slug, err
+}
+
+// Consume counts a view of a snippet with a view limit and returns it. After
+// its last view the snippet is deleted, in the same transaction.
+// The UPDATE locks the row, so of two readers racing for the last view one
+// gets ErrNoRecord, as does anyone once the snippet is gone.
+func (m *SnippetModel) Consume(ctx context.Context, id int) (*Snippet, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt := `UPDATE snippets SET views = views + 1
+WHERE id = ? AND max_views > 0 AND views < max_views AND expires > UTC_TIMESTAMP()`
+
+	result, err := tx.ExecContext(ctx, stmt, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = requireRow(result); err != nil {
+		return nil, err
+	}
+	s, err := get(ctx, tx, "s.id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if s.Views >= s.MaxViews {
+		if _, err = tx.ExecContext(ctx, `DELETE FROM snippets WHERE id = ?`, id); err != nil {
+			return nil, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Update replaces the fields of an existing snippet, recording the new title
+// and content as a revision by userID, and resets its expiry to the given
+// number of days from now. Only the title and content are part of the history.
+func (m *SnippetModel) Update(ctx context.Context, id, userID int, f SnippetFields) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `UPDATE snippets SET title = ?, content = ?, language = ?, visibility = ?,
+    expires = DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY)
+WHERE id = ?`
+
+	_, err = tx.ExecContext(ctx, stmt, f.Title, f.Content, f.Language, f.Visibility, f.Expires, id)
+	if err != nil {
+		return err
+	}
+	err = insertRevision(ctx, tx, id, userID, f.Title, f.Content)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM snippet_tags WHERE snippet_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	err = insertTags(ctx, tx, id, f.Tags)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Restore makes an earlier version the current title and content of a
+// snippet. The old revision is left untouched and a new one is appended, so
+// restoring can itself be undone. The expiry is not changed.
+func (m *SnippetModel) Restore(ctx context.Context, id, userID, version int) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var title, content string
+	stmt := `SELECT title, content FROM snippet_revisions WHERE snippet_id = ? AND version = ?`
+	err = tx.QueryRowContext(ctx, stmt, id, version).Scan(&title, &content)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+
+	stmt = `UPDATE snippets SET title = ?, content = ? WHERE id = ?`
+	_, err = tx.ExecContext(ctx, stmt, title, content, id)
+	if err != nil {
+		return err
+	}
+	err = insertRevision(ctx, tx, id, userID, title, content)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete permanently removes a snippet. Its revisions are removed along with
+// it by the snippet_revisions foreign key.
+func (m *SnippetModel) Delete(ctx context.Context, id int) error {
+	stmt := `DELETE FROM snippets WHERE id = ?`
+
+	result, err := m.DB.ExecContext(ctx, stmt, id)
+	if err != nil {
+		return err
+	}
+	return requireRow(result)
+}
+
+// DeleteExpired permanently removes up to limit snippets whose expiry has
+// passed, along with their revisions, and returns how many were removed.
+func (m *SnippetModel) DeleteExpired(ctx context.Context, limit int) (int, error) {
+	stmt := `DELETE FROM snippets WHERE expires <= UTC_TIMESTAMP() ORDER BY expires LIMIT ?`
+
+	result, err := m.DB.ExecContext(ctx, stmt, limit)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// requireRow returns ErrNoRecord if a statement did not affect any rows.
+func requireRow(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+/*
+Find builds its WHERE clause from the filters that are set. Each sort order pages on a unique key
+so the cursor condition can't skip or repeat rows: the id for newest and oldest (ids grow along
+with created), and title then id for title, as two snippets can have the same title.
+*/
+func (m *SnippetModel) Find(ctx context.Context, q ListQuery) (*SnippetPage, error) {
+	cursor, after, err := q.Cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	where := []string{"s.expires > UTC_TIMESTAMP()"}
+	args := []any{}
+	if q.ViewerID != 0 {
+		where = append(where, "(s.visibility = 'public' OR s.user_id = ?)")
+		args = append(args, q.ViewerID)
+	} else {
+		where = append(where, "s.visibility = 'public'")
+	}
+	if q.AuthorID != 0 {
+		where = append(where, "s.user_id = ?")
+		args = append(args, q.AuthorID)
+	}
+	if q.Tag != "" {
+		where = append(where, "EXISTS (SELECT 1 FROM snippet_tags t WHERE t.snippet_id = s.id AND t.tag = ?)")
+		args = append(args, q.Tag)
+	}
+	if q.Language != "" {
+		where = append(where, "s.language = ?")
+		args = append(args, q.Language)
+	}
+	if !q.From.IsZero() {
+		where = append(where, "s.created >= ?")
+		args = append(args, q.From.UTC())
+	}
+	if !q.To.IsZero() {
+		where = append(where, "s.created < ?")
+		args = append(args, q.To.UTC())
+	}
+
+	var order string
+	switch q.Order() {
+	case SortOldest:
+		order = "s.id ASC"
+		if after {
+			where = append(where, "s.id > ?")
+			args = append(args, cursor.ID)
+		}
+	case SortTitle:
+		order = "s.title ASC, s.id ASC"
+		if after {
+			where = append(where, "(s.title > ? OR (s.title = ? AND s.id > ?))")
+			args = append(args, cursor.Title, cursor.Title, cursor.ID)
+		}
+	default:
+		order = "s.id DESC"
+		if after {
+			where = append(where, "s.id < ?")
+			args = append(args, cursor.ID)
+		}
+	}
+
+	stmt := `SELECT s.id, s.slug, s.user_id, u.name, s.title, s.content, s.language, s.visibility, s.max_views, s.views, s.created, s.expires
+FROM snippets s INNER JOIN users u ON u.id = s.user_id
+WHERE ` + strings.Join(where, " AND ") + `
+ORDER BY ` + order + ` LIMIT ?`
+
+	// One more than a page, to find out whether there is a next one.
+	snippets, err := m.query(ctx, stmt, append(args, q.Limit+1)...)
+	if err != nil {
+		return nil, err
+	}
+	return NewSnippetPage(q, snippets), nil
+}
+
+// query runs a statement returning snippet rows and scans them into a slice.
+func (m *SnippetModel) query(ctx context.Context, stmt string, args ...any) ([]*Snippet, error) {
+	rows, err := m.DB.QueryContext(ctx, stmt, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	snippets := []*Snippet{}
 	/*
-	   In Latest(), s := &Snippet{} is created inside a loop, and we append multiple such pointers to a slice.
+	   In query(), s := &Snippet{} is created inside a loop, and we append multiple such pointers to a slice.
 	   Each iteration creates a new Snippet and its pointer is added to the snippets slice.
 	*/
 	for rows.Next() {
 		s := &Snippet{}
-		err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+		err = rows.Scan(&s.ID, &s.Slug, &s.UserID, &s.Author, &s.Title, &s.Content, &s.Language, &s.Visibility, &s.MaxViews, &s.Views, &s.Created, &s.Expires)
 		if err != nil {
 			return nil, err
 		}
@@ -114,6 +431,60 @@ WHERE expires > UTC_TIMESTAMP() ORDER BY id DESC LIMIT 10`
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
+	if err = loadTags(ctx, m.DB, snippets); err != nil {
+		return nil, err
+	}
 
 	return snippets, nil
 }
+
+// insertTags adds tags to a snippet as part of tx.
+// querier is what *sql.DB and *sql.Tx have in common, so that the same
+// queries can be run in a transaction or outside of one.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func insertTags(ctx context.Context, tx *sql.Tx, snippetID int, tags []string) error {
+	for _, tag := range tags {
+		_, err := tx.ExecContext(ctx, `INSERT INTO snippet_tags (snippet_id, tag) VALUES(?, ?)`, snippetID, tag)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTags fills in the Tags of snippets with a single query, rather than one
+// per snippet.
+func loadTags(ctx context.Context, db querier, snippets []*Snippet) error {
+	if len(snippets) == 0 {
+		return nil
+	}
+	byID := make(map[int]*Snippet, len(snippets))
+	args := make([]any, len(snippets))
+	for i, s := range snippets {
+		s.Tags = []string{}
+		byID[s.ID] = s
+		args[i] = s.ID
+	}
+
+	stmt := `SELECT snippet_id, tag FROM snippet_tags
+WHERE snippet_id IN (?` + strings.Repeat(", ?", len(snippets)-1) + `) ORDER BY tag`
+
+	rows, err := db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var tag string
+		if err = rows.Scan(&id, &tag); err != nil {
+			return err
+		}
+		byID[id].Tags = append(byID[id].Tags, tag)
+	}
+	return rows.Err()
+}
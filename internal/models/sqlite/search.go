@@ -0,0 +1,62 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+	"github.com/Vanshikav123/ByteFlow.git/internal/search"
+)
+
+// SearchModel searches snippets with the snippets_fts FTS5 table, which
+// triggers keep in step with the snippets table.
+type SearchModel struct {
+	DB *sql.DB
+}
+
+var _ models.SearchModelInterface = (*SearchModel)(nil)
+
+// Search runs the terms as prefix queries, `"term1"* "term2"*`, which FTS5
+// ANDs together, and ranks by bm25 with title matches counting double. bm25
+// is lower for better matches, so the score is its negation.
+func (m *SearchModel) Search(ctx context.Context, query string, viewerID, limit, offset int) ([]*models.SearchResult, error) {
+	terms := search.Terms(query)
+	if len(terms) == 0 {
+		return []*models.SearchResult{}, nil
+	}
+	match := `"` + strings.Join(terms, `"* "`) + `"*`
+
+	stmt := `SELECT s.id, s.slug, s.user_id, u.name, s.title, s.content, s.language, s.visibility, s.max_views, s.views, s.created, s.expires, -bm25(snippets_fts, 2.0, 1.0)
+FROM snippets_fts
+INNER JOIN snippets s ON s.id = snippets_fts.rowid
+INNER JOIN users u ON u.id = s.user_id
+WHERE snippets_fts MATCH ? AND s.expires > datetime('now') AND (s.visibility = 'public' OR s.user_id = ?)
+ORDER BY bm25(snippets_fts, 2.0, 1.0), s.id DESC LIMIT ? OFFSET ?`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, match, viewerID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*models.SearchResult{}
+	snippets := []*models.Snippet{}
+	for rows.Next() {
+		s := &models.Snippet{}
+		var score float64
+		err = rows.Scan(&s.ID, &s.Slug, &s.UserID, &s.Author, &s.Title, &s.Content, &s.Language, &s.Visibility, &s.MaxViews, &s.Views, &s.Created, &s.Expires, &score)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, models.NewSearchResult(s, score, terms))
+		snippets = append(snippets, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if err = loadTags(ctx, m.DB, snippets); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,378 @@
+// Package sqlite implements the models store interfaces on SQLite, for
+// deployments that want a single binary and a database file instead of a
+// MySQL server. It expects the modernc.org/sqlite driver, which parses
+// DATETIME columns into time.Time, and a connection with foreign keys on.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+)
+
+type SnippetModel struct {
+	DB   *sql.DB
+	Cost int // bcrypt cost for snippet passwords; zero means models.DefaultBcryptCost
+}
+
+var _ models.SnippetModelInterface = (*SnippetModel)(nil)
+
+// Times are written by SQLite itself as 'YYYY-MM-DD HH:MM:SS' UTC strings, so
+// they compare correctly as text against datetime('now').
+
+func (m *SnippetModel) Insert(ctx context.Context, userID int, f models.SnippetFields) (int, string, error) {
+	slug, err := models.NewSlug()
+	if err != nil {
+		return 0, "", err
+	}
+	hashedPassword, err := models.HashSnippetPassword(f.Password, m.Cost)
+	if err != nil {
+		return 0, "", err
+	}
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback()
+
+	stmt := `INSERT INTO snippets (slug, user_id, title, content, language, visibility, max_views, hashed_password, created, expires)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?, datetime('now'), datetime('now', printf('+%d days', ?)))`
+
+	result, err := tx.ExecContext(ctx, stmt, slug, userID, f.Title, f.Content, f.Language, f.Visibility, f.MaxViews, hashedPassword, f.Expires)
+	if err != nil {
+		return 0, "", err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, "", err
+	}
+
+	err = insertRevision(ctx, tx, int(id), userID, f.Title, f.Content)
+	if err != nil {
+		return 0, "", err
+	}
+	err = insertTags(ctx, tx, int(id), f.Tags)
+	if err != nil {
+		return 0, "", err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, "", err
+	}
+	return int(id), slug, nil
+}
+
+func (m *SnippetModel) Get(ctx context.Context, id int) (*models.Snippet, error) {
+	return get(ctx, m.DB, "s.id = ?", id)
+}
+
+func (m *SnippetModel) GetBySlug(ctx context.Context, slug string) (*models.Snippet, error) {
+	return get(ctx, m.DB, "s.slug = ?", slug)
+}
+
+func get(ctx context.Context, db querier, cond string, arg any) (*models.Snippet, error) {
+	stmt := `SELECT s.id, s.slug, s.user_id, u.name, s.title, s.content, s.language, s.visibility, s.max_views, s.views, s.hashed_password, s.created, s.expires
+FROM snippets s INNER JOIN users u ON u.id = s.user_id
+WHERE s.expires > datetime('now') AND ` + cond
+
+	s := &models.Snippet{}
+	err := db.QueryRowContext(ctx, stmt, arg).Scan(&s.ID, &s.Slug, &s.UserID, &s.Author, &s.Title, &s.Content, &s.Language, &s.Visibility, &s.MaxViews, &s.Views, &s.HashedPassword, &s.Created, &s.Expires)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		}
+		return nil, err
+	}
+	if err = loadTags(ctx, db, []*models.Snippet{s}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (m *SnippetModel) LegacySlug(ctx context.Context, id int) (string, error) {
+	stmt := `SELECT slug FROM snippets
+WHERE id = ? AND legacy AND visibility = 'public' AND expires > datetime('now')`
+
+	var slug string
+	err := m.DB.QueryRowContext(ctx, stmt, id).Scan(&slug)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", models.ErrNoRecord
+	}
+	return slug, err
+}
+
+func (m *SnippetModel) Consume(ctx context.Context, id int) (*models.Snippet, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt := `UPDATE snippets SET views = views + 1
+WHERE id = ? AND max_views > 0 AND views < max_views AND expires > datetime('now')`
+
+	result, err := tx.ExecContext(ctx, stmt, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = requireRow(result); err != nil {
+		return nil, err
+	}
+	s, err := get(ctx, tx, "s.id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if s.Views >= s.MaxViews {
+		if _, err = tx.ExecContext(ctx, `DELETE FROM snippets WHERE id = ?`, id); err != nil {
+			return nil, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (m *SnippetModel) Update(ctx context.Context, id, userID int, f models.SnippetFields) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `UPDATE snippets SET title = ?, content = ?, language = ?, visibility = ?,
+    expires = datetime('now', printf('+%d days', ?))
+WHERE id = ?`
+
+	_, err = tx.ExecContext(ctx, stmt, f.Title, f.Content, f.Language, f.Visibility, f.Expires, id)
+	if err != nil {
+		return err
+	}
+	err = insertRevision(ctx, tx, id, userID, f.Title, f.Content)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM snippet_tags WHERE snippet_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	err = insertTags(ctx, tx, id, f.Tags)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *SnippetModel) Restore(ctx context.Context, id, userID, version int) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var title, content string
+	stmt := `SELECT title, content FROM snippet_revisions WHERE snippet_id = ? AND version = ?`
+	err = tx.QueryRowContext(ctx, stmt, id, version).Scan(&title, &content)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrNoRecord
+		}
+		return err
+	}
+
+	stmt = `UPDATE snippets SET title = ?, content = ? WHERE id = ?`
+	_, err = tx.ExecContext(ctx, stmt, title, content, id)
+	if err != nil {
+		return err
+	}
+	err = insertRevision(ctx, tx, id, userID, title, content)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *SnippetModel) Delete(ctx context.Context, id int) error {
+	stmt := `DELETE FROM snippets WHERE id = ?`
+
+	result, err := m.DB.ExecContext(ctx, stmt, id)
+	if err != nil {
+		return err
+	}
+	return requireRow(result)
+}
+
+// Find pages on the id, or on the title then the id when sorting by title.
+// Titles are compared without case, as MySQL's collation does. The date
+// filters are compared as text, formatted the way SQLite stores times.
+func (m *SnippetModel) Find(ctx context.Context, q models.ListQuery) (*models.SnippetPage, error) {
+	cursor, after, err := q.Cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	where := []string{"s.expires > datetime('now')"}
+	args := []any{}
+	if q.ViewerID != 0 {
+		where = append(where, "(s.visibility = 'public' OR s.user_id = ?)")
+		args = append(args, q.ViewerID)
+	} else {
+		where = append(where, "s.visibility = 'public'")
+	}
+	if q.AuthorID != 0 {
+		where = append(where, "s.user_id = ?")
+		args = append(args, q.AuthorID)
+	}
+	if q.Tag != "" {
+		where = append(where, "EXISTS (SELECT 1 FROM snippet_tags t WHERE t.snippet_id = s.id AND t.tag = ?)")
+		args = append(args, q.Tag)
+	}
+	if q.Language != "" {
+		where = append(where, "s.language = ?")
+		args = append(args, q.Language)
+	}
+	if !q.From.IsZero() {
+		where = append(where, "s.created >= ?")
+		args = append(args, q.From.UTC().Format(time.DateTime))
+	}
+	if !q.To.IsZero() {
+		where = append(where, "s.created < ?")
+		args = append(args, q.To.UTC().Format(time.DateTime))
+	}
+
+	var order string
+	switch q.Order() {
+	case models.SortOldest:
+		order = "s.id ASC"
+		if after {
+			where = append(where, "s.id > ?")
+			args = append(args, cursor.ID)
+		}
+	case models.SortTitle:
+		order = "s.title COLLATE NOCASE ASC, s.id ASC"
+		if after {
+			where = append(where, "(s.title COLLATE NOCASE, s.id) > (?, ?)")
+			args = append(args, cursor.Title, cursor.ID)
+		}
+	default:
+		order = "s.id DESC"
+		if after {
+			where = append(where, "s.id < ?")
+			args = append(args, cursor.ID)
+		}
+	}
+
+	stmt := `SELECT s.id, s.slug, s.user_id, u.name, s.title, s.content, s.language, s.visibility, s.max_views, s.views, s.created, s.expires
+FROM snippets s INNER JOIN users u ON u.id = s.user_id
+WHERE ` + strings.Join(where, " AND ") + `
+ORDER BY ` + order + ` LIMIT ?`
+
+	snippets, err := m.query(ctx, stmt, append(args, q.Limit+1)...)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewSnippetPage(q, snippets), nil
+}
+
+// DeleteExpired removes up to limit expired snippets. There's no DELETE ...
+// LIMIT here, so the rows are picked by a subquery.
+func (m *SnippetModel) DeleteExpired(ctx context.Context, limit int) (int, error) {
+	stmt := `DELETE FROM snippets WHERE id IN (
+SELECT id FROM snippets WHERE expires <= datetime('now') ORDER BY expires LIMIT ?)`
+
+	result, err := m.DB.ExecContext(ctx, stmt, limit)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+func (m *SnippetModel) query(ctx context.Context, stmt string, args ...any) ([]*models.Snippet, error) {
+	rows, err := m.DB.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snippets := []*models.Snippet{}
+	for rows.Next() {
+		s := &models.Snippet{}
+		err = rows.Scan(&s.ID, &s.Slug, &s.UserID, &s.Author, &s.Title, &s.Content, &s.Language, &s.Visibility, &s.MaxViews, &s.Views, &s.Created, &s.Expires)
+		if err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if err = loadTags(ctx, m.DB, snippets); err != nil {
+		return nil, err
+	}
+	return snippets, nil
+}
+
+// requireRow returns models.ErrNoRecord if a statement did not affect any rows.
+func requireRow(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrNoRecord
+	}
+	return nil
+}
+
+// querier is what *sql.DB and *sql.Tx have in common, so that the same
+// queries can be run in a transaction or outside of one.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func insertTags(ctx context.Context, tx *sql.Tx, snippetID int, tags []string) error {
+	for _, tag := range tags {
+		_, err := tx.ExecContext(ctx, `INSERT INTO snippet_tags (snippet_id, tag) VALUES(?, ?)`, snippetID, tag)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTags fills in the Tags of snippets with a single query.
+func loadTags(ctx context.Context, db querier, snippets []*models.Snippet) error {
+	if len(snippets) == 0 {
+		return nil
+	}
+	byID := make(map[int]*models.Snippet, len(snippets))
+	args := make([]any, len(snippets))
+	for i, s := range snippets {
+		s.Tags = []string{}
+		byID[s.ID] = s
+		args[i] = s.ID
+	}
+
+	stmt := `SELECT snippet_id, tag FROM snippet_tags
+WHERE snippet_id IN (?` + strings.Repeat(", ?", len(snippets)-1) + `) ORDER BY tag`
+
+	rows, err := db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var tag string
+		if err = rows.Scan(&id, &tag); err != nil {
+			return err
+		}
+		byID[id].Tags = append(byID[id].Tags, tag)
+	}
+	return rows.Err()
+}
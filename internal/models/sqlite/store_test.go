@@ -0,0 +1,31 @@
+package sqlite
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models/storetest"
+	_ "modernc.org/sqlite"
+)
+
+func TestConformance(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db") + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	storetest.Migrate(t, db, "sqlite")
+
+	storetest.Run(t, &storetest.Stores{
+		Snippets: &SnippetModel{DB: db, Cost: 4},
+		Users:    &UserModel{DB: db, Cost: 4},
+		Expire: func(t *testing.T, id int) {
+			_, err := db.Exec(`UPDATE snippets SET expires = datetime('now', '-1 minute') WHERE id = ?`, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+		},
+	})
+}
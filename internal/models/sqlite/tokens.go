@@ -0,0 +1,90 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+)
+
+type TokenModel struct {
+	DB *sql.DB
+}
+
+var _ models.TokenModelInterface = (*TokenModel)(nil)
+
+func (m *TokenModel) Insert(ctx context.Context, userID int, name string, expires int) (string, error) {
+	plaintext, hash, err := models.NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	stmt := `INSERT INTO api_tokens (user_id, name, hash, created, expires)
+VALUES(?, ?, ?, datetime('now'), CASE WHEN ? > 0 THEN datetime('now', printf('+%d days', ?)) END)`
+
+	_, err = m.DB.ExecContext(ctx, stmt, userID, name, hash, expires, expires)
+	if err != nil {
+		return "", err
+	}
+	return plaintext, nil
+}
+
+func (m *TokenModel) All(ctx context.Context, userID int) ([]*models.Token, error) {
+	stmt := `SELECT id, user_id, name, created, expires, last_used FROM api_tokens
+WHERE user_id = ? ORDER BY id DESC`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*models.Token{}
+	for rows.Next() {
+		t := &models.Token{}
+		var expires, lastUsed sql.NullTime
+		err = rows.Scan(&t.ID, &t.UserID, &t.Name, &t.Created, &expires, &lastUsed)
+		if err != nil {
+			return nil, err
+		}
+		t.Expires, t.LastUsed = expires.Time, lastUsed.Time
+		tokens = append(tokens, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (m *TokenModel) Delete(ctx context.Context, id, userID int) error {
+	stmt := `DELETE FROM api_tokens WHERE id = ? AND user_id = ?`
+
+	result, err := m.DB.ExecContext(ctx, stmt, id, userID)
+	if err != nil {
+		return err
+	}
+	return requireRow(result)
+}
+
+func (m *TokenModel) Authenticate(ctx context.Context, plaintext string) (int, error) {
+	hash := models.HashToken(plaintext)
+
+	var id, userID int
+	stmt := `SELECT id, user_id FROM api_tokens
+WHERE hash = ? AND (expires IS NULL OR expires > datetime('now'))`
+	err := m.DB.QueryRowContext(ctx, stmt, hash).Scan(&id, &userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, models.ErrInvalidCredentials
+		}
+		return 0, err
+	}
+
+	stmt = `UPDATE api_tokens SET last_used = datetime('now') WHERE id = ?`
+	_, err = m.DB.ExecContext(ctx, stmt, id)
+	if err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+	"golang.org/x/crypto/bcrypt"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type UserModel struct {
+	DB   *sql.DB
+	Cost int // bcrypt cost for new passwords; zero means models.DefaultBcryptCost
+}
+
+var _ models.UserModelInterface = (*UserModel)(nil)
+
+func (m *UserModel) Insert(ctx context.Context, name, email, password string) error {
+	hashedPassword, err := models.HashPassword(password, m.Cost)
+	if err != nil {
+		return err
+	}
+	stmt := `INSERT INTO users (name, email, hashed_password, created)
+VALUES(?, ?, ?, datetime('now'))`
+
+	_, err = m.DB.ExecContext(ctx, stmt, name, email, string(hashedPassword))
+	if err != nil {
+		// SQLite doesn't report the name of the violated index, only the
+		// columns, e.g. "UNIQUE constraint failed: users.email".
+		var sqliteError *sqlite.Error
+		if errors.As(err, &sqliteError) {
+			if sqliteError.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE && strings.Contains(sqliteError.Error(), "users.email") {
+				return models.ErrDuplicateEmail
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func (m *UserModel) Authenticate(ctx context.Context, email, password string) (int, error) {
+	var id int
+	var hashedPassword []byte
+
+	// Emails match whatever their case, see users_uc_email_nocase.
+	stmt := "SELECT id, hashed_password FROM users WHERE email = ? COLLATE NOCASE"
+	err := m.DB.QueryRowContext(ctx, stmt, email).Scan(&id, &hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, models.ErrInvalidCredentials
+		}
+		return 0, err
+	}
+
+	err = bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return 0, models.ErrInvalidCredentials
+		}
+		return 0, err
+	}
+	return id, nil
+}
+
+func (m *UserModel) Exists(ctx context.Context, id int) (bool, error) {
+	var exists bool
+
+	stmt := "SELECT EXISTS(SELECT true FROM users WHERE id = ?)"
+	err := m.DB.QueryRowContext(ctx, stmt, id).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,41 @@
+package models_test
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+	"github.com/Vanshikav123/ByteFlow.git/internal/models/storetest"
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// TestConformance needs a database to run against, e.g.
+//
+//	BYTEFLOW_TEST_MYSQL_DSN="web:pass@/byteflow_test?parseTime=true" go test ./internal/models
+//
+// The schema is migrated up first. Users and snippets are left behind, but
+// every run makes its own so the database can be reused.
+func TestConformance(t *testing.T) {
+	dsn := os.Getenv("BYTEFLOW_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("BYTEFLOW_TEST_MYSQL_DSN not set")
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	storetest.Migrate(t, db, "mysql")
+
+	storetest.Run(t, &storetest.Stores{
+		Snippets: &models.SnippetModel{DB: db, Cost: 4},
+		Users:    &models.UserModel{DB: db, Cost: 4},
+		Expire: func(t *testing.T, id int) {
+			_, err := db.Exec(`UPDATE snippets SET expires = DATE_SUB(UTC_TIMESTAMP(), INTERVAL 1 MINUTE) WHERE id = ?`, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+		},
+	})
+}
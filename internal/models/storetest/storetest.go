@@ -0,0 +1,269 @@
+// Package storetest is the conformance test of the models backends. Every
+// backend runs Run against its own stores, so MySQL, Postgres, SQLite and the
+// in-memory store can't drift apart in the rules the handlers rely on.
+package storetest
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/migrate"
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+	"github.com/Vanshikav123/ByteFlow.git/migrations"
+)
+
+// Stores are the models of one backend. Expire makes the snippet with the id
+// expired, as if its expiry time had passed, which the models themselves have
+// no way of doing.
+type Stores struct {
+	Snippets models.SnippetModelInterface
+	Users    models.UserModelInterface
+	Expire   func(t *testing.T, id int)
+}
+
+// Run runs the conformance tests against s. The stores may be shared with
+// other tests or earlier runs: every test signs up its own users and only
+// looks at their snippets.
+func Run(t *testing.T, s *Stores) {
+	t.Run("DuplicateEmail", func(t *testing.T) { testDuplicateEmail(t, s) })
+	t.Run("Authenticate", func(t *testing.T) { testAuthenticate(t, s) })
+	t.Run("Expiry", func(t *testing.T) { testExpiry(t, s) })
+	t.Run("Visibility", func(t *testing.T) { testVisibility(t, s) })
+	t.Run("Consume", func(t *testing.T) { testConsume(t, s) })
+}
+
+// Migrate brings the schema of db up to date, as "web migrate up" does.
+func Migrate(t *testing.T, db *sql.DB, driver string) {
+	t.Helper()
+	fsys, err := fs.Sub(migrations.Files, driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &migrate.Migrator{DB: db, Driver: driver, FS: fsys}
+	if _, err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const password = "pa$$word"
+
+// uniqueEmail returns an address no earlier test or run has used.
+func uniqueEmail(t *testing.T) string {
+	t.Helper()
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return "user-" + hex.EncodeToString(b) + "@example.com"
+}
+
+// newUser signs up a user and returns their id.
+func newUser(t *testing.T, s *Stores) int {
+	t.Helper()
+	ctx := context.Background()
+	email := uniqueEmail(t)
+	if err := s.Users.Insert(ctx, "Test User", email, password); err != nil {
+		t.Fatal(err)
+	}
+	id, err := s.Users.Authenticate(ctx, email, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// newSnippet inserts a snippet by userID and returns its id.
+func newSnippet(t *testing.T, s *Stores, userID int, f models.SnippetFields) int {
+	t.Helper()
+	if f.Title == "" {
+		f.Title = "Title"
+	}
+	if f.Content == "" {
+		f.Content = "Content"
+	}
+	if f.Visibility == "" {
+		f.Visibility = models.VisibilityPublic
+	}
+	if f.Expires == 0 {
+		f.Expires = 7
+	}
+	id, _, err := s.Snippets.Insert(context.Background(), userID, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// found returns the ids of the snippets by authorID that Find lists for
+// viewerID.
+func found(t *testing.T, s *Stores, authorID, viewerID int) []int {
+	t.Helper()
+	page, err := s.Snippets.Find(context.Background(), models.ListQuery{ViewerID: viewerID, AuthorID: authorID, Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, snippet := range page.Snippets {
+		ids = append(ids, snippet.ID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func testDuplicateEmail(t *testing.T, s *Stores) {
+	ctx := context.Background()
+	email := uniqueEmail(t)
+	if err := s.Users.Insert(ctx, "First", email, password); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dup := range []string{email, strings.ToUpper(email), strings.ToUpper(email[:1]) + email[1:]} {
+		err := s.Users.Insert(ctx, "Second", dup, password)
+		if !errors.Is(err, models.ErrDuplicateEmail) {
+			t.Errorf("signing up again as %q: got %v, want ErrDuplicateEmail", dup, err)
+		}
+	}
+}
+
+func testAuthenticate(t *testing.T, s *Stores) {
+	ctx := context.Background()
+	email := uniqueEmail(t)
+	if err := s.Users.Insert(ctx, "User", email, password); err != nil {
+		t.Fatal(err)
+	}
+	id, err := s.Users.Authenticate(ctx, email, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Users.Authenticate(ctx, strings.ToUpper(email), password)
+	if err != nil || got != id {
+		t.Errorf("logging in with the email in upper case: got %d, %v, want %d", got, err, id)
+	}
+	if _, err := s.Users.Authenticate(ctx, email, "wrong password"); !errors.Is(err, models.ErrInvalidCredentials) {
+		t.Errorf("wrong password: got %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := s.Users.Authenticate(ctx, uniqueEmail(t), password); !errors.Is(err, models.ErrInvalidCredentials) {
+		t.Errorf("unknown email: got %v, want ErrInvalidCredentials", err)
+	}
+	if exists, err := s.Users.Exists(ctx, id); err != nil || !exists {
+		t.Errorf("Exists(%d) = %v, %v", id, exists, err)
+	}
+}
+
+func testExpiry(t *testing.T, s *Stores) {
+	ctx := context.Background()
+	userID := newUser(t, s)
+	live := newSnippet(t, s, userID, models.SnippetFields{})
+	expired := newSnippet(t, s, userID, models.SnippetFields{})
+	snippet, err := s.Snippets.Get(ctx, expired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Expire(t, expired)
+
+	if _, err := s.Snippets.Get(ctx, expired); !errors.Is(err, models.ErrNoRecord) {
+		t.Errorf("Get of an expired snippet: got %v, want ErrNoRecord", err)
+	}
+	if _, err := s.Snippets.GetBySlug(ctx, snippet.Slug); !errors.Is(err, models.ErrNoRecord) {
+		t.Errorf("GetBySlug of an expired snippet: got %v, want ErrNoRecord", err)
+	}
+	if snippet, err := s.Snippets.Get(ctx, live); err != nil || snippet.UserID != userID {
+		t.Errorf("Get of a live snippet: got %v, %v", snippet, err)
+	}
+	if got := found(t, s, userID, userID); !slices.Equal(got, []int{live}) {
+		t.Errorf("Find listed %v, want only the live snippet %d", got, live)
+	}
+
+	n, err := s.Snippets.DeleteExpired(ctx, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n < 1 {
+		t.Errorf("DeleteExpired deleted %d snippets, want at least the expired one", n)
+	}
+	if _, err := s.Snippets.Get(ctx, live); err != nil {
+		t.Errorf("DeleteExpired deleted a live snippet: %v", err)
+	}
+}
+
+func testVisibility(t *testing.T, s *Stores) {
+	ctx := context.Background()
+	owner := newUser(t, s)
+	other := newUser(t, s)
+	public := newSnippet(t, s, owner, models.SnippetFields{Visibility: models.VisibilityPublic})
+	unlisted := newSnippet(t, s, owner, models.SnippetFields{Visibility: models.VisibilityUnlisted})
+	private := newSnippet(t, s, owner, models.SnippetFields{Visibility: models.VisibilityPrivate})
+
+	// Get returns snippets whatever their visibility, VisibleTo decides who
+	// may see them.
+	for id, want := range map[int]models.Visibility{public: models.VisibilityPublic, unlisted: models.VisibilityUnlisted, private: models.VisibilityPrivate} {
+		snippet, err := s.Snippets.Get(ctx, id)
+		if err != nil {
+			t.Fatalf("Get(%d): %v", id, err)
+		}
+		if snippet.Visibility != want || snippet.UserID != owner {
+			t.Errorf("Get(%d) returned a %s snippet of user %d, want a %s one of %d", id, snippet.Visibility, snippet.UserID, want, owner)
+		}
+		bySlug, err := s.Snippets.GetBySlug(ctx, snippet.Slug)
+		if err != nil || bySlug.ID != id {
+			t.Errorf("GetBySlug(%q) = %v, %v, want snippet %d", snippet.Slug, bySlug, err, id)
+		}
+	}
+
+	// Listings show everyone the public snippets and the owner all of theirs.
+	tests := []struct {
+		name   string
+		viewer int
+		want   []int
+	}{
+		{"anonymous", 0, []int{public}},
+		{"other user", other, []int{public}},
+		{"owner", owner, []int{public, unlisted, private}},
+	}
+	for _, tt := range tests {
+		if got := found(t, s, owner, tt.viewer); !slices.Equal(got, tt.want) {
+			t.Errorf("Find for %s listed %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func testConsume(t *testing.T, s *Stores) {
+	ctx := context.Background()
+	userID := newUser(t, s)
+	limited := newSnippet(t, s, userID, models.SnippetFields{Visibility: models.VisibilityUnlisted, MaxViews: 2})
+	unlimited := newSnippet(t, s, userID, models.SnippetFields{})
+
+	for views := 1; views <= 2; views++ {
+		snippet, err := s.Snippets.Consume(ctx, limited)
+		if err != nil {
+			t.Fatalf("view %d: %v", views, err)
+		}
+		if snippet.Views != views || snippet.ViewsLeft() != 2-views {
+			t.Errorf("view %d: got %d views, %d left", views, snippet.Views, snippet.ViewsLeft())
+		}
+		if snippet.Content != "Content" {
+			t.Errorf("view %d: got content %q", views, snippet.Content)
+		}
+	}
+	if _, err := s.Snippets.Consume(ctx, limited); !errors.Is(err, models.ErrNoRecord) {
+		t.Errorf("view after the last: got %v, want ErrNoRecord", err)
+	}
+	if _, err := s.Snippets.Get(ctx, limited); !errors.Is(err, models.ErrNoRecord) {
+		t.Errorf("Get after the last view: got %v, want ErrNoRecord", err)
+	}
+
+	if _, err := s.Snippets.Consume(ctx, unlimited); !errors.Is(err, models.ErrNoRecord) {
+		t.Errorf("Consume of a snippet without a view limit: got %v, want ErrNoRecord", err)
+	}
+	if snippet, err := s.Snippets.Get(ctx, unlimited); err != nil || snippet.Views != 0 {
+		t.Errorf("Get of a snippet without a view limit: got %v, %v", snippet, err)
+	}
+}
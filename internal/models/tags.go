@@ -0,0 +1,52 @@
+package models
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Languages are the values a snippet's Language can take, besides "" for
+// not saying. They are what the listings can be filtered by, so it's a fixed
+// list rather than whatever people type.
+var Languages = []string{
+	"bash", "c", "cpp", "csharp", "css", "go", "html", "java", "javascript", "json", "kotlin",
+	"markdown", "php", "python", "ruby", "rust", "sql", "swift", "text", "typescript", "yaml",
+}
+
+// ValidLanguage reports whether language is "" or one of Languages.
+func ValidLanguage(language string) bool {
+	return language == "" || slices.Contains(Languages, language)
+}
+
+// Tag limits. Tags are lower case, start with a letter or digit and may also
+// contain + # . and -, so "c++", "c#" and "node.js" all work.
+const (
+	MaxTags      = 5
+	MaxTagLength = 32
+)
+
+var tagRX = regexp.MustCompile(`^[a-z0-9][a-z0-9+#.-]*$`)
+
+// ValidTag reports whether tag, already normalised by NormalizeTags, is one a
+// snippet can have.
+func ValidTag(tag string) bool {
+	return len(tag) <= MaxTagLength && tagRX.MatchString(tag)
+}
+
+// NormalizeTags splits every value on commas and whitespace, so tags can come
+// from a single form field as well as a JSON array, lower-cases them and drops
+// repeats. The tags come back sorted, the order every backend lists them in.
+func NormalizeTags(values []string) []string {
+	tags := []string{}
+	for _, v := range values {
+		for _, tag := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r' }) {
+			tag = strings.ToLower(tag)
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	slices.Sort(tags)
+	return tags
+}
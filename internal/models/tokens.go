@@ -0,0 +1,137 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"time"
+)
+
+// TokenModelInterface is what the web application needs from an API token store.
+type TokenModelInterface interface {
+	Insert(ctx context.Context, userID int, name string, expires int) (string, error)
+	All(ctx context.Context, userID int) ([]*Token, error)
+	Delete(ctx context.Context, id, userID int) error
+	Authenticate(ctx context.Context, plaintext string) (int, error)
+}
+
+// Token is a personal API token. Only a SHA-256 hash of the token is stored,
+// so the plaintext is shown to the user once, when it is created. Expires and
+// LastUsed are the zero time when the token never expires or hasn't been used.
+type Token struct {
+	ID       int       `json:"id"`
+	UserID   int       `json:"user_id"`
+	Name     string    `json:"name"`
+	Created  time.Time `json:"created"`
+	Expires  time.Time `json:"expires"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+type TokenModel struct {
+	DB *sql.DB
+}
+
+// tokenPrefix makes tokens easy to recognise in config files and secret scanners.
+const tokenPrefix = "bf_"
+
+// NewToken generates a random plaintext token and the hash to store for it.
+func NewToken() (plaintext string, hash []byte, err error) {
+	b := make([]byte, 20)
+	if _, err = rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	plaintext = tokenPrefix + base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
+	return plaintext, HashToken(plaintext), nil
+}
+
+// HashToken returns the value stored in place of a plaintext token.
+func HashToken(plaintext string) []byte {
+	h := sha256.Sum256([]byte(plaintext))
+	return h[:]
+}
+
+// Insert creates a token for a user and returns its plaintext. expires is a
+// number of days, or 0 for a token that never expires.
+func (m *TokenModel) Insert(ctx context.Context, userID int, name string, expires int) (string, error) {
+	plaintext, hash, err := NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	stmt := `INSERT INTO api_tokens (user_id, name, hash, created, expires)
+VALUES(?, ?, ?, UTC_TIMESTAMP(), IF(? > 0, DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY), NULL))`
+
+	_, err = m.DB.ExecContext(ctx, stmt, userID, name, hash, expires, expires)
+	if err != nil {
+		return "", err
+	}
+	return plaintext, nil
+}
+
+// All returns every token belonging to a user, including expired ones, newest first.
+func (m *TokenModel) All(ctx context.Context, userID int) ([]*Token, error) {
+	stmt := `SELECT id, user_id, name, created, expires, last_used FROM api_tokens
+WHERE user_id = ? ORDER BY id DESC`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*Token{}
+	for rows.Next() {
+		t := &Token{}
+		var expires, lastUsed sql.NullTime
+		err = rows.Scan(&t.ID, &t.UserID, &t.Name, &t.Created, &expires, &lastUsed)
+		if err != nil {
+			return nil, err
+		}
+		t.Expires, t.LastUsed = expires.Time, lastUsed.Time
+		tokens = append(tokens, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Delete revokes one of a user's tokens. It returns ErrNoRecord if the token
+// doesn't exist or belongs to another user.
+func (m *TokenModel) Delete(ctx context.Context, id, userID int) error {
+	stmt := `DELETE FROM api_tokens WHERE id = ? AND user_id = ?`
+
+	result, err := m.DB.ExecContext(ctx, stmt, id, userID)
+	if err != nil {
+		return err
+	}
+	return requireRow(result)
+}
+
+// Authenticate looks up an unexpired token and returns the id of the user it
+// belongs to, recording that the token was used. It returns
+// ErrInvalidCredentials for unknown, revoked or expired tokens.
+func (m *TokenModel) Authenticate(ctx context.Context, plaintext string) (int, error) {
+	hash := HashToken(plaintext)
+
+	var id, userID int
+	stmt := `SELECT id, user_id FROM api_tokens
+WHERE hash = ? AND (expires IS NULL OR expires > UTC_TIMESTAMP())`
+	err := m.DB.QueryRowContext(ctx, stmt, hash).Scan(&id, &userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvalidCredentials
+		}
+		return 0, err
+	}
+
+	stmt = `UPDATE api_tokens SET last_used = UTC_TIMESTAMP() WHERE id = ?`
+	_, err = m.DB.ExecContext(ctx, stmt, id)
+	if err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
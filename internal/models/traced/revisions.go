@@ -0,0 +1,29 @@
+package traced
+
+import (
+	"context"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type RevisionModel struct {
+	Next models.RevisionModelInterface
+}
+
+var _ models.RevisionModelInterface = (*RevisionModel)(nil)
+
+func (m *RevisionModel) All(ctx context.Context, snippetID int) ([]*models.Revision, error) {
+	ctx, span := start(ctx, "RevisionModel.All", attribute.Int("snippet.id", snippetID))
+	revisions, err := m.Next.All(ctx, snippetID)
+	span.SetAttributes(attribute.Int("revisions.count", len(revisions)))
+	end(span, err)
+	return revisions, err
+}
+
+func (m *RevisionModel) Get(ctx context.Context, snippetID, version int) (*models.Revision, error) {
+	ctx, span := start(ctx, "RevisionModel.Get", attribute.Int("snippet.id", snippetID), attribute.Int("revision.version", version))
+	r, err := m.Next.Get(ctx, snippetID, version)
+	end(span, err)
+	return r, err
+}
@@ -0,0 +1,24 @@
+package traced
+
+import (
+	"context"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type SearchModel struct {
+	Next models.SearchModelInterface
+}
+
+var _ models.SearchModelInterface = (*SearchModel)(nil)
+
+// Search leaves the query itself out of the span, since people search for
+// things they wouldn't want in a trace backend.
+func (m *SearchModel) Search(ctx context.Context, query string, viewerID, limit, offset int) ([]*models.SearchResult, error) {
+	ctx, span := start(ctx, "SearchModel.Search", attribute.Int("user.id", viewerID), attribute.Int("limit", limit), attribute.Int("offset", offset))
+	results, err := m.Next.Search(ctx, query, viewerID, limit, offset)
+	span.SetAttributes(attribute.Int("results.count", len(results)))
+	end(span, err)
+	return results, err
+}
@@ -0,0 +1,104 @@
+package traced
+
+import (
+	"context"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type SnippetModel struct {
+	Next models.SnippetModelInterface
+}
+
+var _ models.SnippetModelInterface = (*SnippetModel)(nil)
+
+func (m *SnippetModel) Insert(ctx context.Context, userID int, f models.SnippetFields) (int, string, error) {
+	ctx, span := start(ctx, "SnippetModel.Insert", attribute.Int("user.id", userID), attribute.Int("snippet.expires_days", f.Expires), attribute.Int("snippet.max_views", f.MaxViews))
+	id, slug, err := m.Next.Insert(ctx, userID, f)
+	span.SetAttributes(attribute.Int("snippet.id", id), attribute.String("snippet.slug", slug))
+	end(span, err)
+	return id, slug, err
+}
+
+func (m *SnippetModel) Get(ctx context.Context, id int) (*models.Snippet, error) {
+	ctx, span := start(ctx, "SnippetModel.Get", attribute.Int("snippet.id", id))
+	s, err := m.Next.Get(ctx, id)
+	end(span, err)
+	return s, err
+}
+
+func (m *SnippetModel) GetBySlug(ctx context.Context, slug string) (*models.Snippet, error) {
+	ctx, span := start(ctx, "SnippetModel.GetBySlug", attribute.String("snippet.slug", slug))
+	s, err := m.Next.GetBySlug(ctx, slug)
+	if s != nil {
+		span.SetAttributes(attribute.Int("snippet.id", s.ID))
+	}
+	end(span, err)
+	return s, err
+}
+
+func (m *SnippetModel) LegacySlug(ctx context.Context, id int) (string, error) {
+	ctx, span := start(ctx, "SnippetModel.LegacySlug", attribute.Int("snippet.id", id))
+	slug, err := m.Next.LegacySlug(ctx, id)
+	end(span, err)
+	return slug, err
+}
+
+func (m *SnippetModel) Consume(ctx context.Context, id int) (*models.Snippet, error) {
+	ctx, span := start(ctx, "SnippetModel.Consume", attribute.Int("snippet.id", id))
+	s, err := m.Next.Consume(ctx, id)
+	if s != nil {
+		span.SetAttributes(attribute.Int("snippet.views_left", s.ViewsLeft()))
+	}
+	end(span, err)
+	return s, err
+}
+
+func (m *SnippetModel) Update(ctx context.Context, id, userID int, f models.SnippetFields) error {
+	ctx, span := start(ctx, "SnippetModel.Update", attribute.Int("snippet.id", id), attribute.Int("user.id", userID))
+	err := m.Next.Update(ctx, id, userID, f)
+	end(span, err)
+	return err
+}
+
+func (m *SnippetModel) Restore(ctx context.Context, id, userID, version int) error {
+	ctx, span := start(ctx, "SnippetModel.Restore", attribute.Int("snippet.id", id), attribute.Int("user.id", userID), attribute.Int("revision.version", version))
+	err := m.Next.Restore(ctx, id, userID, version)
+	end(span, err)
+	return err
+}
+
+func (m *SnippetModel) Delete(ctx context.Context, id int) error {
+	ctx, span := start(ctx, "SnippetModel.Delete", attribute.Int("snippet.id", id))
+	err := m.Next.Delete(ctx, id)
+	end(span, err)
+	return err
+}
+
+// Find records the filters but not the cursor, which is opaque anyway.
+func (m *SnippetModel) Find(ctx context.Context, q models.ListQuery) (*models.SnippetPage, error) {
+	ctx, span := start(ctx, "SnippetModel.Find",
+		attribute.Int("user.id", q.ViewerID),
+		attribute.Int("limit", q.Limit),
+		attribute.String("sort", string(q.Order())),
+		attribute.Int("filter.author_id", q.AuthorID),
+		attribute.String("filter.tag", q.Tag),
+		attribute.String("filter.language", q.Language),
+		attribute.Bool("cursor", q.After != ""),
+	)
+	page, err := m.Next.Find(ctx, q)
+	if page != nil {
+		span.SetAttributes(attribute.Int("snippets.count", len(page.Snippets)), attribute.Bool("has_more", page.Next != ""))
+	}
+	end(span, err)
+	return page, err
+}
+
+func (m *SnippetModel) DeleteExpired(ctx context.Context, limit int) (int, error) {
+	ctx, span := start(ctx, "SnippetModel.DeleteExpired", attribute.Int("limit", limit))
+	n, err := m.Next.DeleteExpired(ctx, limit)
+	span.SetAttributes(attribute.Int("snippets.deleted", n))
+	end(span, err)
+	return n, err
+}
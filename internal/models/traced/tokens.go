@@ -0,0 +1,46 @@
+package traced
+
+import (
+	"context"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// TokenModel never records plaintext tokens on its spans.
+type TokenModel struct {
+	Next models.TokenModelInterface
+}
+
+var _ models.TokenModelInterface = (*TokenModel)(nil)
+
+func (m *TokenModel) Insert(ctx context.Context, userID int, name string, expires int) (string, error) {
+	ctx, span := start(ctx, "TokenModel.Insert", attribute.Int("user.id", userID))
+	token, err := m.Next.Insert(ctx, userID, name, expires)
+	end(span, err)
+	return token, err
+}
+
+func (m *TokenModel) All(ctx context.Context, userID int) ([]*models.Token, error) {
+	ctx, span := start(ctx, "TokenModel.All", attribute.Int("user.id", userID))
+	tokens, err := m.Next.All(ctx, userID)
+	end(span, err)
+	return tokens, err
+}
+
+func (m *TokenModel) Delete(ctx context.Context, id, userID int) error {
+	ctx, span := start(ctx, "TokenModel.Delete", attribute.Int("token.id", id), attribute.Int("user.id", userID))
+	err := m.Next.Delete(ctx, id, userID)
+	end(span, err)
+	return err
+}
+
+func (m *TokenModel) Authenticate(ctx context.Context, plaintext string) (int, error) {
+	ctx, span := start(ctx, "TokenModel.Authenticate")
+	id, err := m.Next.Authenticate(ctx, plaintext)
+	if err == nil {
+		span.SetAttributes(attribute.Int("user.id", id))
+	}
+	end(span, err)
+	return id, err
+}
@@ -0,0 +1,39 @@
+// Package traced wraps the model interfaces so every call gets an
+// OpenTelemetry span, named after the model and method, e.g.
+// "SnippetModel.Get". It works with any backend; the SQL the call runs shows
+// up as child spans when the database connection is instrumented too.
+package traced
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/Vanshikav123/ByteFlow.git/internal/models")
+
+func start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// end finishes span, recording err. The models sentinel errors are expected
+// outcomes, like a missing snippet or a wrong password, so they are noted as
+// an attribute without marking the span as failed.
+func end(span trace.Span, err error) {
+	switch {
+	case err == nil:
+	case errors.Is(err, models.ErrNoRecord),
+		errors.Is(err, models.ErrInvalidCredentials),
+		errors.Is(err, models.ErrDuplicateEmail):
+		span.SetAttributes(attribute.String("models.error", err.Error()))
+	default:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,40 @@
+package traced
+
+import (
+	"context"
+
+	"github.com/Vanshikav123/ByteFlow.git/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// UserModel records no email addresses or passwords on its spans.
+type UserModel struct {
+	Next models.UserModelInterface
+}
+
+var _ models.UserModelInterface = (*UserModel)(nil)
+
+func (m *UserModel) Insert(ctx context.Context, name, email, password string) error {
+	ctx, span := start(ctx, "UserModel.Insert")
+	err := m.Next.Insert(ctx, name, email, password)
+	end(span, err)
+	return err
+}
+
+func (m *UserModel) Authenticate(ctx context.Context, email, password string) (int, error) {
+	ctx, span := start(ctx, "UserModel.Authenticate")
+	id, err := m.Next.Authenticate(ctx, email, password)
+	if err == nil {
+		span.SetAttributes(attribute.Int("user.id", id))
+	}
+	end(span, err)
+	return id, err
+}
+
+func (m *UserModel) Exists(ctx context.Context, id int) (bool, error) {
+	ctx, span := start(ctx, "UserModel.Exists", attribute.Int("user.id", id))
+	exists, err := m.Next.Exists(ctx, id)
+	span.SetAttributes(attribute.Bool("user.exists", exists))
+	end(span, err)
+	return exists, err
+}
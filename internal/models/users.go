@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"strings"
@@ -10,6 +11,14 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// UserModelInterface is what the web application needs from a user store.
+// UserModel implements it on top of MySQL.
+type UserModelInterface interface {
+	Insert(ctx context.Context, name, email, password string) error
+	Authenticate(ctx context.Context, email, password string) (int, error)
+	Exists(ctx context.Context, id int) (bool, error)
+}
+
 // user struct conaining user credemtials
 type User struct {
 	ID             int
@@ -19,8 +28,22 @@ type User struct {
 	Created        time.Time
 }
 
+// DefaultBcryptCost is the bcrypt cost used when a user model's Cost is zero.
+const DefaultBcryptCost = 12
+
+// HashPassword hashes a password with bcrypt at the given cost, or at
+// DefaultBcryptCost if cost is zero. Every backend's UserModel uses it so they
+// all produce interchangeable hashes.
+func HashPassword(password string, cost int) ([]byte, error) {
+	if cost == 0 {
+		cost = DefaultBcryptCost
+	}
+	return bcrypt.GenerateFromPassword([]byte(password), cost)
+}
+
 type UserModel struct {
-	DB *sql.DB
+	DB   *sql.DB
+	Cost int // bcrypt cost for new passwords; zero means DefaultBcryptCost
 }
 
 /*
@@ -29,20 +52,20 @@ type UserModel struct {
 	    // Store newHash in the database
 	}
 */
-func (m *UserModel) Insert(name, email, password string) error {
-	/*bcrypt.GenerateFromPassword([]byte(password), 12):
+func (m *UserModel) Insert(ctx context.Context, name, email, password string) error {
+	/*bcrypt.GenerateFromPassword([]byte(password), cost):
 	This function hashes the provided password using the bcrypt algorithm.
 	[]byte(password) converts the password string into a byte slice because bcrypt operates on byte slices.
-	12 is the cost factor (computational complexity), which determines how expensive the hashing operation is.
-	Higher values make it slower but more secure.*/
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	The cost factor (computational complexity) determines how expensive the hashing operation is.
+	Higher values make it slower but more secure. It comes from m.Cost, see HashPassword.*/
+	hashedPassword, err := HashPassword(password, m.Cost)
 	if err != nil {
 		return err
 	}
 	stmt := `INSERT INTO users (name, email, hashed_password, created)
 VALUES(?, ?, ?, UTC_TIMESTAMP())`
 
-	_, err = m.DB.Exec(stmt, name, email, string(hashedPassword))
+	_, err = m.DB.ExecContext(ctx, stmt, name, email, string(hashedPassword))
 	/*
 				sqlErr, ok := err.(*mysql.MySQLError) → Extracts MySQL error details directly.
 				If err is of type mysql.MySQLError, errors.As() stores it in mySQLError
@@ -63,7 +86,7 @@ VALUES(?, ?, ?, UTC_TIMESTAMP())`
 	return nil
 }
 
-func (m *UserModel) Authenticate(email, password string) (int, error) {
+func (m *UserModel) Authenticate(ctx context.Context, email, password string) (int, error) {
 	var id int
 	var hashedPassword []byte
 	/*password is a string.
@@ -71,7 +94,7 @@ func (m *UserModel) Authenticate(email, password string) (int, error) {
 	bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost).
 	This method converts the string into a hash and returns it as a byte slice.*/
 	stmt := "SELECT id, hashed_password FROM users WHERE email = ?"
-	err := m.DB.QueryRow(stmt, email).Scan(&id, &hashedPassword)
+	err := m.DB.QueryRowContext(ctx, stmt, email).Scan(&id, &hashedPassword)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return 0, ErrInvalidCredentials
@@ -92,12 +115,12 @@ func (m *UserModel) Authenticate(email, password string) (int, error) {
 	return id, nil
 }
 
-func (m *UserModel) Exists(id int) (bool, error) {
+func (m *UserModel) Exists(ctx context.Context, id int) (bool, error) {
 	var exists bool
 	/*EXISTS: This is a special SQL operator used to test if a subquery returns any rows.
 	If the subquery returns at least one row, the EXISTS operator evaluates to true; otherwise,
 	it evaluates to false*/
 	stmt := "SELECT EXISTS(SELECT true FROM users WHERE id = ?)"
-	err := m.DB.QueryRow(stmt, id).Scan(&exists)
+	err := m.DB.QueryRowContext(ctx, stmt, id).Scan(&exists)
 	return exists, err
 }
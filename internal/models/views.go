@@ -0,0 +1,24 @@
+package models
+
+/*
+A snippet can be given a view limit, after which it's deleted: with a limit of one it's "burnt after
+reading", which is how a password is handed to someone once. Only the views of other people count,
+so the author can check on it, and every view is counted by Consume, which deletes the snippet in
+the same transaction as the last view so no two readers can both get it.
+
+Snippets with a view limit are never public; they'd be read by whoever happened to list them.
+*/
+
+// MaxViewLimit is the highest view limit a snippet can be given.
+const MaxViewLimit = 100
+
+// Limited reports whether the snippet has a view limit.
+func (s *Snippet) Limited() bool {
+	return s.MaxViews > 0
+}
+
+// ViewsLeft returns how many more times the snippet can be viewed before it's
+// deleted, if it has a view limit.
+func (s *Snippet) ViewsLeft() int {
+	return max(s.MaxViews-s.Views, 0)
+}
@@ -0,0 +1,32 @@
+package models
+
+import "slices"
+
+// Visibility says who can see a snippet.
+type Visibility string
+
+const (
+	// VisibilityPublic snippets are listed, searchable and readable by anyone.
+	VisibilityPublic Visibility = "public"
+	// VisibilityUnlisted snippets are readable by anyone with the link, but
+	// only listed and found by search for their author.
+	VisibilityUnlisted Visibility = "unlisted"
+	// VisibilityPrivate snippets are only readable by their author.
+	VisibilityPrivate Visibility = "private"
+)
+
+// Visibilities lists the levels in the order they're offered to users; the
+// first one is the default.
+var Visibilities = []Visibility{VisibilityPublic, VisibilityUnlisted, VisibilityPrivate}
+
+// ValidVisibility reports whether v is one of Visibilities.
+func ValidVisibility(v Visibility) bool {
+	return slices.Contains(Visibilities, v)
+}
+
+// VisibleTo reports whether the user with the given id, or 0 for an anonymous
+// visitor, may read the snippet. Get returns snippets of every visibility, so
+// the handlers check this before showing one.
+func (s *Snippet) VisibleTo(userID int) bool {
+	return s.Visibility != VisibilityPrivate || (userID != 0 && s.UserID == userID)
+}
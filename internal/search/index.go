@@ -0,0 +1,174 @@
+package search
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+)
+
+// The fields of a document. A match in the title counts for more than one in
+// the content, as it does with the SQL backends.
+const (
+	fieldTitle = iota
+	fieldContent
+	numFields
+)
+
+var fieldWeights = [numFields]float64{2, 1}
+
+// BM25 parameters: k1 is how quickly repeating a word stops adding to the
+// score, b how much long documents are penalised. These are the usual values.
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+/*
+Index is an in-process inverted index of titles and contents, for backends without a full-text
+index of their own. It maps every word to the documents containing it and how often, and scores
+matches with BM25, the ranking function SQLite's FTS5 uses too.
+
+Prefix matching looks at every word in the index, which is fine for the number of snippets an
+in-memory backend holds. An Index is not safe for concurrent use.
+*/
+type Index struct {
+	docs     map[int]*document
+	postings map[string]map[int]*[numFields]int // word -> document id -> occurrences per field
+	totalLen [numFields]int
+}
+
+type document struct {
+	words  []string // distinct words, to find the postings to remove
+	length [numFields]int
+}
+
+// Hit is a document matching a search and its score; higher is better.
+type Hit struct {
+	ID    int
+	Score float64
+}
+
+func NewIndex() *Index {
+	return &Index{
+		docs:     make(map[int]*document),
+		postings: make(map[string]map[int]*[numFields]int),
+	}
+}
+
+// Put indexes a document, replacing whatever was indexed under id before.
+func (ix *Index) Put(id int, title, content string) {
+	ix.Remove(id)
+
+	doc := &document{}
+	for field, text := range [numFields]string{title, content} {
+		tokens := tokenize(text)
+		doc.length[field] = len(tokens)
+		ix.totalLen[field] += len(tokens)
+		for _, t := range tokens {
+			docs, ok := ix.postings[t.word]
+			if !ok {
+				docs = make(map[int]*[numFields]int)
+				ix.postings[t.word] = docs
+			}
+			counts, ok := docs[id]
+			if !ok {
+				counts = new([numFields]int)
+				docs[id] = counts
+				doc.words = append(doc.words, t.word)
+			}
+			counts[field]++
+		}
+	}
+	ix.docs[id] = doc
+}
+
+// Remove takes a document out of the index. Removing one that isn't there
+// does nothing.
+func (ix *Index) Remove(id int) {
+	doc, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+	for _, word := range doc.words {
+		delete(ix.postings[word], id)
+		if len(ix.postings[word]) == 0 {
+			delete(ix.postings, word)
+		}
+	}
+	for field := range numFields {
+		ix.totalLen[field] -= doc.length[field]
+	}
+	delete(ix.docs, id)
+}
+
+// Search returns the documents in which every term starts a word, best
+// first. Documents with equal scores come newest (highest id) first.
+func (ix *Index) Search(terms []string) []Hit {
+	if len(terms) == 0 || len(ix.docs) == 0 {
+		return nil
+	}
+
+	var avgLen [numFields]float64
+	for field := range numFields {
+		avgLen[field] = max(float64(ix.totalLen[field])/float64(len(ix.docs)), 1)
+	}
+
+	var scores map[int]float64
+	for _, term := range terms {
+		// Add up the occurrences of every word the term is a prefix of.
+		counts := make(map[int]*[numFields]int)
+		for word, docs := range ix.postings {
+			if !strings.HasPrefix(word, term) {
+				continue
+			}
+			for id, c := range docs {
+				sum, ok := counts[id]
+				if !ok {
+					sum = new([numFields]int)
+					counts[id] = sum
+				}
+				for field := range numFields {
+					sum[field] += c[field]
+				}
+			}
+		}
+
+		n, df := float64(len(ix.docs)), float64(len(counts))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		termScores := make(map[int]float64, len(counts))
+		for id, c := range counts {
+			// Every term must match, so only documents that matched all the
+			// terms before this one are kept.
+			if scores != nil {
+				if _, ok := scores[id]; !ok {
+					continue
+				}
+			}
+			doc := ix.docs[id]
+			score := scores[id]
+			for field := range numFields {
+				tf := float64(c[field])
+				norm := 1 - b + b*float64(doc.length[field])/avgLen[field]
+				score += fieldWeights[field] * idf * tf * (k1 + 1) / (tf + k1*norm)
+			}
+			termScores[id] = score
+		}
+		scores = termScores
+		if len(scores) == 0 {
+			return nil
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+	slices.SortFunc(hits, func(x, y Hit) int {
+		if c := cmp.Compare(y.Score, x.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(y.ID, x.ID)
+	})
+	return hits
+}
@@ -0,0 +1,195 @@
+// Package search has the parts of full-text search that don't depend on a
+// database: splitting queries and text into terms, highlighting matches, and
+// Index, an in-process inverted index for the in-memory backend. The SQL
+// backends search with their own full-text indexes but parse queries and
+// highlight results with this package, so a query finds and shows the same
+// things whichever backend runs it.
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxTerms is the most terms of a query that are used; the rest are ignored.
+const MaxTerms = 10
+
+// Terms splits a query into lower-cased words. A word is a run of letters and
+// digits and anything else separates words, so the query can't contain
+// operators for any backend's query syntax. Repeated words are dropped.
+//
+// A snippet matches when every term is the start of some word in its title or
+// content: "conf" finds "config" and "configuration".
+func Terms(query string) []string {
+	var terms []string
+	for _, t := range tokenize(query) {
+		if len(terms) == MaxTerms {
+			break
+		}
+		if !contains(terms, t.word) {
+			terms = append(terms, t.word)
+		}
+	}
+	return terms
+}
+
+func contains(terms []string, word string) bool {
+	for _, t := range terms {
+		if t == word {
+			return true
+		}
+	}
+	return false
+}
+
+// token is a word of a text and where it is, as byte offsets.
+type token struct {
+	word       string
+	start, end int
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		switch {
+		case isWordRune(r) && start < 0:
+			start = i
+		case !isWordRune(r) && start >= 0:
+			tokens = append(tokens, token{strings.ToLower(text[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{strings.ToLower(text[start:]), start, len(text)})
+	}
+	return tokens
+}
+
+// matches reports whether word starts with any of the terms.
+func matches(word string, terms []string) bool {
+	for _, t := range terms {
+		if strings.HasPrefix(word, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Span is a piece of highlighted text. Match is true for words that matched
+// the query.
+type Span struct {
+	Text  string `json:"text"`
+	Match bool   `json:"match,omitempty"`
+}
+
+// Fragment is a run of text split into spans. Templates print the spans in
+// order, marking up the matches; nothing in the text is HTML.
+type Fragment []Span
+
+// Highlight returns all of text with the words matching terms marked.
+func Highlight(text string, terms []string) Fragment {
+	var f Fragment
+	last := 0
+	for _, t := range tokenize(text) {
+		if !matches(t.word, terms) {
+			continue
+		}
+		if t.start > last {
+			f = append(f, Span{Text: text[last:t.start]})
+		}
+		f = append(f, Span{Text: text[t.start:t.end], Match: true})
+		last = t.end
+	}
+	if last < len(text) {
+		f = append(f, Span{Text: text[last:]})
+	}
+	return f
+}
+
+// ellipsis marks where an excerpt was cut out of a longer text.
+const ellipsis = "…"
+
+/*
+Excerpts returns up to n highlighted fragments of text, each about width bytes long and cut at
+word boundaries, around the first matches of terms. Matches close together share a fragment. If
+nothing in text matches, which happens when only the title did, the single fragment is the start
+of the text.
+*/
+func Excerpts(text string, terms []string, width, n int) []Fragment {
+	var excerpts []Fragment
+	end := 0
+	for _, t := range tokenize(text) {
+		if len(excerpts) == n {
+			break
+		}
+		if t.start < end || !matches(t.word, terms) {
+			continue
+		}
+		// Show a little of what comes before the match, more of what follows,
+		// without showing again what the excerpt before ended with.
+		start := max(t.start-width/4, end)
+		var f Fragment
+		f, end = excerpt(text, start, t.start, min(start+width, len(text)), terms)
+		excerpts = append(excerpts, f)
+	}
+	if len(excerpts) == 0 && text != "" {
+		f, _ := excerpt(text, 0, 0, min(width, len(text)), terms)
+		excerpts = append(excerpts, f)
+	}
+	return excerpts
+}
+
+// excerpt highlights text[start:end], first moving start forward to a word
+// boundary before anchor, where the match is, and end back to one after it,
+// and adds ellipses where text was cut. It returns where the excerpt ended up
+// ending, so the next one can start after it.
+func excerpt(text string, start, anchor, end int, terms []string) (Fragment, int) {
+	if start > 0 {
+		for start < anchor && !utf8.RuneStart(text[start]) {
+			start++
+		}
+		if !boundary(text, start) {
+			if i := strings.IndexFunc(text[start:anchor], unicode.IsSpace); i >= 0 {
+				start += i
+			}
+		}
+		start = anchor - len(strings.TrimLeftFunc(text[start:anchor], unicode.IsSpace))
+	}
+	if end < len(text) {
+		for end > anchor && !utf8.RuneStart(text[end]) {
+			end--
+		}
+		if !boundary(text, end) {
+			if i := strings.LastIndexFunc(text[anchor:end], unicode.IsSpace); i > 0 {
+				end = anchor + i
+			}
+		}
+		end = anchor + len(strings.TrimRightFunc(text[anchor:end], unicode.IsSpace))
+	}
+
+	f := Highlight(text[start:end], terms)
+	if start > 0 {
+		f = append(Fragment{{Text: ellipsis}}, f...)
+	}
+	if end < len(text) {
+		f = append(f, Span{Text: ellipsis})
+	}
+	return f, end
+}
+
+// boundary reports whether text can be cut at byte i without cutting a word
+// in two.
+func boundary(text string, i int) bool {
+	if i <= 0 || i >= len(text) {
+		return true
+	}
+	before, _ := utf8.DecodeLastRuneInString(text[:i])
+	after, _ := utf8.DecodeRuneInString(text[i:])
+	return !isWordRune(before) || !isWordRune(after)
+}
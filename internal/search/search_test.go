@@ -0,0 +1,231 @@
+package search
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// show writes a fragment as its text with the matches in brackets.
+func show(f Fragment) string {
+	var sb strings.Builder
+	for _, s := range f {
+		if s.Match {
+			sb.WriteString("[" + s.Text + "]")
+		} else {
+			sb.WriteString(s.Text)
+		}
+	}
+	return sb.String()
+}
+
+func showAll(fs []Fragment) []string {
+	var out []string
+	for _, f := range fs {
+		out = append(out, show(f))
+	}
+	return out
+}
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"empty", "", nil},
+		{"only separators", "  ;-- \"*\" ", nil},
+		{"lower cased", "Hello WORLD", []string{"hello", "world"}},
+		{"punctuation separates", "foo_bar a-b c.d", []string{"foo", "bar", "a", "b", "c", "d"}},
+		{"operators are words", `title:go OR "exact" NOT -x*`, []string{"title", "go", "or", "exact", "not", "x"}},
+		{"digits", "i18n 42 v2", []string{"i18n", "42", "v2"}},
+		{"repeats dropped", "go Go GO gopher go", []string{"go", "gopher"}},
+		{"non-ASCII", "Café NAÏVE Ärger", []string{"café", "naïve", "ärger"}},
+		{"no spaces between words", "日本語のテキスト", []string{"日本語のテキスト"}},
+		{"at most MaxTerms", "a b c d e f g h i j k l", []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Terms(tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("Terms(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		query string
+		want  string
+	}{
+		{"no match", "nothing here", "zzz", "nothing here"},
+		{"empty text", "", "go", ""},
+		{"whole words", "go to the gopher", "go", "[go] to the [gopher]"},
+		{"prefix only", "ago cargo go", "go", "ago cargo [go]"},
+		{"case kept", "Config CONFIG config", "conf", "[Config] [CONFIG] [config]"},
+		{"at start and end", "go and go", "go", "[go] and [go]"},
+		{"adjacent matches", "go,go;go", "go", "[go],[go];[go]"},
+		{"several terms", "read the config file", "file conf", "read the [config] [file]"},
+		{"multi-byte", "naïve café résumé", "CAFÉ rés", "naïve [café] [résumé]"},
+		{"markup is text", "<b>go</b>", "go b", "<[b]>[go]</[b]>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := Highlight(tt.text, Terms(tt.query))
+			if got := show(f); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			var text strings.Builder
+			for _, s := range f {
+				text.WriteString(s.Text)
+			}
+			if text.String() != tt.text {
+				t.Errorf("spans add up to %q, not the text", text.String())
+			}
+		})
+	}
+}
+
+func TestExcerpts(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		query string
+		width int
+		n     int
+		want  []string
+	}{
+		{"empty text", "", "go", 20, 3, nil},
+		{"no match shows the start", "no match here at all", "zzz", 12, 3, []string{"no match…"}},
+		{"no match in a short text", "short", "zzz", 12, 3, []string{"short"}},
+		{"whole text fits", "conf config configuration", "conf", 40, 3, []string{"[conf] [config] [configuration]"}},
+		{"match at start", "needle " + strings.Repeat("x ", 20), "needle", 20, 3, []string{"[needle] x x x x x x x…"}},
+		{"match at end", strings.Repeat("x ", 20) + "needle", "needle", 20, 3, []string{"…x x [needle]"}},
+		{"matches far apart", strings.Repeat("a ", 20) + "needle " + strings.Repeat("b ", 20) + "needle", "needle", 12, 3,
+			[]string{"…a [needle] b…", "…b [needle]"}},
+		{"close matches share an excerpt", "foo bar foo " + strings.Repeat("x ", 20), "foo", 20, 3,
+			[]string{"[foo] bar [foo] x x x x…"}},
+		{"adjacent matches", "aa bb aa bb aa", "aa", 6, 5, []string{"[aa] bb…", "…[aa] bb…", "…[aa]"}},
+		{"no match lost between excerpts", "aa aa aa aa aa aa aa aa", "aa", 8, 3,
+			[]string{"[aa] [aa] [aa]…", "…[aa] [aa]…", "…[aa] [aa]…"}},
+		{"at most n", "aa bb aa bb aa", "aa", 6, 2, []string{"[aa] bb…", "…[aa] bb…"}},
+		{"multi-byte before", strings.Repeat("é", 30) + " needle", "needle", 12, 3, []string{"…[needle]"}},
+		{"multi-byte after", "needle " + strings.Repeat("ü", 30), "needle", 12, 3, []string{"[needle]…"}},
+		{"multi-byte words", "Ünïcödé café naïve résumé", "café rés", 40, 3, []string{"…[café] naïve [résumé]"}},
+		{"no spaces", "日本語のテキスト 検索 日本語", "検索", 12, 3, []string{"…[検索]…"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := showAll(Excerpts(tt.text, Terms(tt.query), tt.width, tt.n))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Every excerpt is a piece of the text, cut on rune boundaries, whatever the
+// width.
+func TestExcerptsAreValidUTF8(t *testing.T) {
+	text := "Ünïcödé café — naïve résumé, 日本語のテキスト 検索 Straße ĳ café"
+	for width := 1; width <= len(text)+1; width++ {
+		for _, f := range Excerpts(text, Terms("café 検索 straße"), width, 5) {
+			for _, s := range f {
+				if !strings.Contains(text, s.Text) && s.Text != ellipsis {
+					t.Fatalf("width %d: %q isn't part of the text", width, s.Text)
+				}
+			}
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	ix := NewIndex()
+	ix.Put(1, "Go config", "How to read a configuration file.")
+	ix.Put(2, "Shell tips", "Find files and grep them. Go to a directory with cd.")
+	ix.Put(3, "Café notes", "Ärger über naïve Configs.")
+	ix.Put(4, "Gophers", "go go go")
+
+	ids := func(hits []Hit) []int {
+		var out []int
+		for _, h := range hits {
+			out = append(out, h.ID)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []int
+	}{
+		{"no terms", "", nil},
+		{"no match", "rust", nil},
+		{"prefix", "conf", []int{1, 3}},
+		{"ranked", "go", []int{4, 1, 2}},
+		{"every term must match", "go file", []int{1, 2}},
+		{"terms in different fields", "shell grep", []int{2}},
+		{"non-ASCII", "CAFÉ ärg", []int{3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(ix.Search(Terms(tt.query))); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	// Putting a document again replaces its words, removing it takes them
+	// all away.
+	ix.Put(1, "Rust config", "Cargo.toml")
+	if got := ids(ix.Search(Terms("go"))); !slices.Equal(got, []int{4, 2}) {
+		t.Errorf("after re-putting 1, go found %v", got)
+	}
+	if got := ids(ix.Search(Terms("rust cargo"))); !slices.Equal(got, []int{1}) {
+		t.Errorf("after re-putting 1, rust cargo found %v", got)
+	}
+	ix.Remove(1)
+	ix.Remove(1)
+	if got := ids(ix.Search(Terms("conf"))); !slices.Equal(got, []int{3}) {
+		t.Errorf("after removing 1, conf found %v", got)
+	}
+	for _, id := range []int{2, 3, 4} {
+		ix.Remove(id)
+	}
+	if len(ix.postings) != 0 || ix.totalLen != [numFields]int{} {
+		t.Errorf("empty index still has %d words and lengths %v", len(ix.postings), ix.totalLen)
+	}
+	if hits := ix.Search(Terms("go")); hits != nil {
+		t.Errorf("empty index found %v", hits)
+	}
+}
+
+func TestIndexRanking(t *testing.T) {
+	tests := []struct {
+		name string
+		docs [][2]string // title and content of documents 1, 2, ...
+		want []int
+	}{
+		{"title counts for more", [][2]string{{"needle", "x"}, {"x", "needle"}}, []int{1, 2}},
+		{"repeats count for more", [][2]string{{"x", "needle needle needle"}, {"x", "needle needle"}, {"x", "needle"}}, []int{1, 2, 3}},
+		{"long documents count for less", [][2]string{{"x", "needle hay"}, {"x", "needle " + strings.Repeat("hay ", 50)}}, []int{1, 2}},
+		{"ties newest first", [][2]string{{"same", "needle"}, {"same", "needle"}, {"same", "needle"}}, []int{3, 2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ix := NewIndex()
+			for i, doc := range tt.docs {
+				ix.Put(i+1, doc[0], doc[1])
+			}
+			var got []int
+			for _, h := range ix.Search(Terms("needle")) {
+				got = append(got, h.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package migrations
+
+import "embed"
+
+// Files holds the schema migrations for each database driver, one directory
+// per driver. Files are named <version>_<name>.up.sql and
+// <version>_<name>.down.sql and are applied in version order by the
+// internal/migrate package.
+//
+//go:embed "mysql" "postgres" "sqlite"
+var Files embed.FS
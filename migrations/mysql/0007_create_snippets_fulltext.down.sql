@@ -0,0 +1,2 @@
+DROP INDEX ft_snippets_title ON snippets;
+DROP INDEX ft_snippets_title_content ON snippets;
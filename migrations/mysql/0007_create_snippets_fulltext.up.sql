@@ -0,0 +1,4 @@
+-- The combined index answers searches; the title-only one lets the ranking
+-- count title matches twice.
+CREATE FULLTEXT INDEX ft_snippets_title_content ON snippets (title, content);
+CREATE FULLTEXT INDEX ft_snippets_title ON snippets (title);
@@ -0,0 +1,4 @@
+DROP TABLE snippet_tags;
+DROP INDEX idx_snippets_title ON snippets;
+DROP INDEX idx_snippets_language ON snippets;
+ALTER TABLE snippets DROP COLUMN language;
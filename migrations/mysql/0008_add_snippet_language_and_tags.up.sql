@@ -0,0 +1,16 @@
+ALTER TABLE snippets ADD COLUMN language VARCHAR(32) NOT NULL DEFAULT '';
+
+-- Listings can be filtered by language and sorted by title.
+CREATE INDEX idx_snippets_language ON snippets (language);
+CREATE INDEX idx_snippets_title ON snippets (title);
+
+-- The primary key finds the tags of a snippet, idx_snippet_tags_tag the
+-- snippets with a tag.
+CREATE TABLE snippet_tags (
+    snippet_id INTEGER NOT NULL,
+    tag VARCHAR(32) NOT NULL,
+    PRIMARY KEY (snippet_id, tag),
+    CONSTRAINT snippet_tags_fk_snippet FOREIGN KEY (snippet_id) REFERENCES snippets (id) ON DELETE CASCADE
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
+
+CREATE INDEX idx_snippet_tags_tag ON snippet_tags (tag, snippet_id);
@@ -0,0 +1,2 @@
+DROP INDEX idx_snippets_visibility ON snippets;
+ALTER TABLE snippets DROP COLUMN visibility;
@@ -0,0 +1,12 @@
+-- Snippets are addressed by a random slug instead of their id. Those created
+-- before this get one too, and are marked legacy so that their old numeric
+-- URLs keep working. ascii_bin makes slugs case-sensitive, as they're base64.
+ALTER TABLE snippets
+    ADD COLUMN slug VARCHAR(16) CHARACTER SET ascii COLLATE ascii_bin NULL,
+    ADD COLUMN legacy BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE snippets SET slug = LOWER(HEX(RANDOM_BYTES(8))), legacy = TRUE;
+
+ALTER TABLE snippets MODIFY COLUMN slug VARCHAR(16) CHARACTER SET ascii COLLATE ascii_bin NOT NULL;
+
+CREATE UNIQUE INDEX idx_snippets_slug ON snippets (slug);
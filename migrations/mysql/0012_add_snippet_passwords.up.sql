@@ -0,0 +1,2 @@
+-- The bcrypt hash of the password needed to read the snippet, if it has one.
+ALTER TABLE snippets ADD COLUMN hashed_password CHAR(60) NULL;
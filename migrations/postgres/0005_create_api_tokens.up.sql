@@ -0,0 +1,10 @@
+CREATE TABLE api_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    hash BYTEA NOT NULL,
+    created TIMESTAMPTZ NOT NULL,
+    expires TIMESTAMPTZ,
+    last_used TIMESTAMPTZ,
+    CONSTRAINT api_tokens_uc_hash UNIQUE (hash)
+);
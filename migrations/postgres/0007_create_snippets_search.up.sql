@@ -0,0 +1,7 @@
+-- The simple configuration lower-cases words without stemming them or
+-- dropping stopwords, which suits code better than a language does.
+ALTER TABLE snippets ADD COLUMN search tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', title), 'A') || setweight(to_tsvector('simple', content), 'B')
+) STORED;
+
+CREATE INDEX idx_snippets_search ON snippets USING GIN (search);
@@ -0,0 +1,5 @@
+-- 'public', 'unlisted' or 'private'. Snippets created before this were all
+-- listed, so they stay public.
+ALTER TABLE snippets ADD COLUMN visibility VARCHAR(16) NOT NULL DEFAULT 'public';
+
+CREATE INDEX idx_snippets_visibility ON snippets (visibility);
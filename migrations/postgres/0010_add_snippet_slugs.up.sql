@@ -0,0 +1,12 @@
+-- Snippets are addressed by a random slug instead of their id. Those created
+-- before this get one too, and are marked legacy so that their old numeric
+-- URLs keep working.
+ALTER TABLE snippets
+    ADD COLUMN slug VARCHAR(16),
+    ADD COLUMN legacy BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE snippets SET slug = substr(md5(gen_random_uuid()::text), 1, 16), legacy = TRUE;
+
+ALTER TABLE snippets ALTER COLUMN slug SET NOT NULL;
+
+CREATE UNIQUE INDEX idx_snippets_slug ON snippets (slug);
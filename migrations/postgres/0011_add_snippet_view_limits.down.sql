@@ -0,0 +1 @@
+ALTER TABLE snippets DROP COLUMN max_views, DROP COLUMN views;
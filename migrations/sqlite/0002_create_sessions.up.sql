@@ -0,0 +1,8 @@
+-- Layout required by github.com/alexedwards/scs/sqlite3store.
+CREATE TABLE sessions (
+    token TEXT PRIMARY KEY,
+    data BLOB NOT NULL,
+    expiry REAL NOT NULL
+);
+
+CREATE INDEX sessions_expiry_idx ON sessions (expiry);
@@ -0,0 +1,12 @@
+-- DATETIME columns hold 'YYYY-MM-DD HH:MM:SS' UTC text written by
+-- datetime('now'); the declared type lets the driver scan them as time.Time.
+CREATE TABLE snippets (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    expires DATETIME NOT NULL
+);
+
+CREATE INDEX idx_snippets_created ON snippets (created);
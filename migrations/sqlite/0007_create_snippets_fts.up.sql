@@ -0,0 +1,24 @@
+-- An external content table: the text is only stored in snippets and the
+-- triggers keep the index in step with it. Diacritics are kept, as they are
+-- when results are highlighted.
+CREATE VIRTUAL TABLE snippets_fts USING fts5(
+    title, content,
+    content = 'snippets', content_rowid = 'id',
+    tokenize = 'unicode61 remove_diacritics 0'
+);
+
+CREATE TRIGGER snippets_fts_insert AFTER INSERT ON snippets BEGIN
+    INSERT INTO snippets_fts (rowid, title, content) VALUES (new.id, new.title, new.content);
+END;
+
+CREATE TRIGGER snippets_fts_delete AFTER DELETE ON snippets BEGIN
+    INSERT INTO snippets_fts (snippets_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+END;
+
+CREATE TRIGGER snippets_fts_update AFTER UPDATE OF title, content ON snippets BEGIN
+    INSERT INTO snippets_fts (snippets_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+    INSERT INTO snippets_fts (rowid, title, content) VALUES (new.id, new.title, new.content);
+END;
+
+-- Index the snippets that already exist.
+INSERT INTO snippets_fts (snippets_fts) VALUES ('rebuild');
@@ -0,0 +1,4 @@
+DROP TABLE snippet_tags;
+DROP INDEX idx_snippets_title;
+DROP INDEX idx_snippets_language;
+ALTER TABLE snippets DROP COLUMN language;
@@ -0,0 +1,15 @@
+ALTER TABLE snippets ADD COLUMN language TEXT NOT NULL DEFAULT '';
+
+-- Listings can be filtered by language and sorted by title.
+CREATE INDEX idx_snippets_language ON snippets (language);
+CREATE INDEX idx_snippets_title ON snippets (title COLLATE NOCASE, id);
+
+-- The primary key finds the tags of a snippet, idx_snippet_tags_tag the
+-- snippets with a tag.
+CREATE TABLE snippet_tags (
+    snippet_id INTEGER NOT NULL REFERENCES snippets (id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (snippet_id, tag)
+) WITHOUT ROWID;
+
+CREATE INDEX idx_snippet_tags_tag ON snippet_tags (tag, snippet_id);
@@ -0,0 +1,3 @@
+DROP INDEX idx_snippets_slug;
+ALTER TABLE snippets DROP COLUMN legacy;
+ALTER TABLE snippets DROP COLUMN slug;
@@ -0,0 +1,9 @@
+-- Snippets are addressed by a random slug instead of their id. Those created
+-- before this get one too, and are marked legacy so that their old numeric
+-- URLs keep working.
+ALTER TABLE snippets ADD COLUMN slug TEXT NOT NULL DEFAULT '';
+ALTER TABLE snippets ADD COLUMN legacy INTEGER NOT NULL DEFAULT 0;
+
+UPDATE snippets SET slug = lower(hex(randomblob(8))), legacy = 1;
+
+CREATE UNIQUE INDEX idx_snippets_slug ON snippets (slug);
@@ -0,0 +1,2 @@
+ALTER TABLE snippets DROP COLUMN views;
+ALTER TABLE snippets DROP COLUMN max_views;
@@ -0,0 +1,4 @@
+-- A snippet with a max_views above 0 is deleted once it has been viewed that
+-- many times by people other than its author.
+ALTER TABLE snippets ADD COLUMN max_views INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE snippets ADD COLUMN views INTEGER NOT NULL DEFAULT 0;